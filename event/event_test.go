@@ -1,6 +1,8 @@
 package event_test
 
 import (
+	"context"
+	"sync/atomic"
 	"testing"
 
 	"github.com/mylxsw/glacier/event"
@@ -50,3 +52,30 @@ func TestPublishEvent(t *testing.T) {
 		ID: "121",
 	})
 }
+
+func TestAsyncPublishEvent(t *testing.T) {
+	eventManager := event.NewEventManager(event.NewAsyncMemoryEventStore(4, 10))
+
+	var count int32
+	eventManager.Listen(func(evt UserCreatedEvent) {
+		atomic.AddInt32(&count, 1)
+	})
+	eventManager.Listen(func(evt UserUpdatedEvent) {
+		panic("listener failed")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := eventManager.Start(ctx)
+
+	for i := 0; i < 5; i++ {
+		_ = eventManager.Publish(UserCreatedEvent{ID: "111"})
+		_ = eventManager.Publish(UserUpdatedEvent{ID: "121"})
+	}
+
+	cancel()
+	<-stopped
+
+	if atomic.LoadInt32(&count) != 5 {
+		t.Errorf("all events should be dispatched before stopped, got %d", count)
+	}
+}
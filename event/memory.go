@@ -2,11 +2,16 @@ package event
 
 import (
 	"context"
+	"runtime/debug"
+	"sync"
+
+	"github.com/mylxsw/glacier/log"
 )
 
 // MemoryEventStore is a event store for sync operations
 type MemoryEventStore struct {
 	async       bool
+	workers     int
 	listeners   map[string][]interface{}
 	manager     Manager
 	asyncEvents chan Event
@@ -16,6 +21,23 @@ type MemoryEventStore struct {
 func NewMemoryEventStore(async bool, capacity int) Store {
 	return &MemoryEventStore{
 		async:       async,
+		workers:     1,
+		listeners:   make(map[string][]interface{}),
+		asyncEvents: make(chan Event, capacity),
+	}
+}
+
+// NewAsyncMemoryEventStore create an event store which dispatches all events asynchronously, Publish returns
+// immediately (unless the queue of capacity is full), and listeners are called by a pool of workers.
+// Panics of listeners are recovered and logged. When workers > 1, the order of events is not guaranteed
+func NewAsyncMemoryEventStore(workers int, capacity int) Store {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &MemoryEventStore{
+		async:       true,
+		workers:     workers,
 		listeners:   make(map[string][]interface{}),
 		asyncEvents: make(chan Event, capacity),
 	}
@@ -49,6 +71,21 @@ func (eventStore *MemoryEventStore) callEvent(evt Event) {
 	}
 }
 
+// callEventAsync call listeners of an async event, panics of listeners are recovered so that the worker keeps running
+func (eventStore *MemoryEventStore) callEventAsync(evt Event) {
+	for _, listener := range eventStore.listeners[evt.Name] {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					log.Errorf("[glacier] event listener for [%s] panic: %v, Stack: \n%s", evt.Name, err, debug.Stack())
+				}
+			}()
+
+			eventStore.manager.Call(evt.Event, listener)
+		}()
+	}
+}
+
 // isAsyncEvent check whether the event is an async event
 func (eventStore *MemoryEventStore) isAsyncEvent(evt interface{}) bool {
 	if eventStore.async {
@@ -67,23 +104,33 @@ func (eventStore *MemoryEventStore) SetManager(manager Manager) {
 func (eventStore *MemoryEventStore) Start(ctx context.Context) <-chan interface{} {
 	stopped := make(chan interface{}, 0)
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				for {
-					select {
-					case evt := <-eventStore.asyncEvents:
-						eventStore.callEvent(evt)
-					default:
-						stopped <- struct{}{}
-						return
+	var wg sync.WaitGroup
+	wg.Add(eventStore.workers)
+	for i := 0; i < eventStore.workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					for {
+						select {
+						case evt := <-eventStore.asyncEvents:
+							eventStore.callEventAsync(evt)
+						default:
+							return
+						}
 					}
+				case evt := <-eventStore.asyncEvents:
+					eventStore.callEventAsync(evt)
 				}
-			case evt := <-eventStore.asyncEvents:
-				eventStore.callEvent(evt)
 			}
-		}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		stopped <- struct{}{}
 	}()
 
 	return stopped
@@ -56,3 +56,14 @@ func SetStoreOption(h func(cc infra.Resolver) Store) Option {
 		p.evtStoreBuilder = h
 	}
 }
+
+// SetAsyncOption 使用异步的方式分发所有事件，Publish 会立即返回，事件监听器由 workers 个协程并发执行，
+// 监听器中的 panic 会被捕获并记录日志，capacity 为事件队列的长度，队列满时 Publish 会阻塞
+// 默认为同步分发，如果监听器依赖事件的顺序，不要使用该选项（workers 大于 1 时不保证事件的执行顺序）
+func SetAsyncOption(workers int, capacity int) Option {
+	return func(p *provider) {
+		p.evtStoreBuilder = func(cc infra.Resolver) Store {
+			return NewAsyncMemoryEventStore(workers, capacity)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package glacier
 
 import (
 	"fmt"
+	"os"
 	"reflect"
 	"sync"
 	"time"
@@ -32,12 +33,18 @@ type framework struct {
 	preBinder          func(binder infra.Binder)
 	beforeServerStop   func(resolver infra.Resolver) error
 	onServerReadyHooks []namedFunc
+	// beforeServerStartHooks are called before daemon providers and services start
+	beforeServerStartHooks []func(resolver infra.Resolver) error
+	onServerStopHooks      []namedFunc
 
 	gracefulBuilder func() infra.Graceful
+	shutdownSignals []os.Signal
+	reloadSignals   []os.Signal
 
 	flagContextInit interface{}
 	singletons      []interface{}
 	prototypes      []interface{}
+	keyedBindings   []keyedBinding
 
 	status   Status
 	nodes    infra.GraphvizNodes
@@ -115,13 +122,27 @@ func (impl *framework) Graceful(builder func() infra.Graceful) infra.Glacier {
 	return impl
 }
 
+// WithShutdownSignals 设置触发优雅停机的信号，默认为 graceful.DefaultShutdownSignals
+func (impl *framework) WithShutdownSignals(sigs ...os.Signal) infra.Glacier {
+	impl.shutdownSignals = sigs
+	return impl
+}
+
+// WithReloadSignals 设置触发 reload 的信号，默认为 graceful.DefaultReloadSignals
+func (impl *framework) WithReloadSignals(sigs ...os.Signal) infra.Glacier {
+	impl.reloadSignals = sigs
+	return impl
+}
+
 // SetLogger set default logger for glacier
 func (impl *framework) SetLogger(logger infra.Logger) infra.Glacier {
 	impl.logger = logger
 	return impl
 }
 
-// Singleton add a singleton instance to container
+// Singleton add a singleton instance to container, the instance is created on first resolution and shared
+// by all resolutions afterwards, including concurrent executions of jobs and http requests, so it must be
+// safe for concurrent use
 func (impl *framework) Singleton(ins ...interface{}) infra.Glacier {
 	if impl.status >= Initialized {
 		panic("[glacier] can not invoke this method after Glacier has been initialize")
@@ -131,7 +152,8 @@ func (impl *framework) Singleton(ins ...interface{}) infra.Glacier {
 	return impl
 }
 
-// Prototype add a prototype to container
+// Prototype add a prototype to container, a new instance is created on every resolution, e.g. every
+// execution of a job which depends on it gets its own instance
 func (impl *framework) Prototype(ins ...interface{}) infra.Glacier {
 	if impl.status >= Initialized {
 		panic("[glacier] can not invoke this method after Glacier has been initialize")
@@ -141,6 +163,34 @@ func (impl *framework) Prototype(ins ...interface{}) infra.Glacier {
 	return impl
 }
 
+// keyedBinding is a binding with a key, it's used when there are multiple instances of the same type
+type keyedBinding struct {
+	key       string
+	init      interface{}
+	prototype bool
+}
+
+// SingletonWithKey add a singleton instance to container with a key, so that multiple instances of the same
+// type can be bound, e.g. the primary and replica *sql.DB. They can be resolved by infra.ResolveWithKey
+func (impl *framework) SingletonWithKey(key string, ins interface{}) infra.Glacier {
+	if impl.status >= Initialized {
+		panic("[glacier] can not invoke this method after Glacier has been initialize")
+	}
+
+	impl.keyedBindings = append(impl.keyedBindings, keyedBinding{key: key, init: ins})
+	return impl
+}
+
+// PrototypeWithKey add a prototype to container with a key, see SingletonWithKey
+func (impl *framework) PrototypeWithKey(key string, ins interface{}) infra.Glacier {
+	if impl.status >= Initialized {
+		panic("[glacier] can not invoke this method after Glacier has been initialize")
+	}
+
+	impl.keyedBindings = append(impl.keyedBindings, keyedBinding{key: key, init: ins, prototype: true})
+	return impl
+}
+
 // Resolve is a proxy to container's Resolve function
 func (impl *framework) Resolve(resolver interface{}) error {
 	return impl.cc.Resolve(resolver)
@@ -23,6 +23,8 @@ type gracefulImpl struct {
 	handlerTimeout time.Duration
 
 	signalChan chan os.Signal
+	// shutdownChan is used by Shutdown, so that it works no matter which signals are used for shutdown
+	shutdownChan chan struct{}
 
 	signalHandler       SignalHandler
 	reloadHandlers      []Handler
@@ -55,6 +57,7 @@ func New(reloadSignals []os.Signal, shutdownSignals []os.Signal, handlerTimeout
 		shutdownHandlers: make([]Handler, 0),
 		handlerTimeout:   handlerTimeout,
 		signalChan:       make(chan os.Signal),
+		shutdownChan:     make(chan struct{}),
 		signalHandler:    signalHandler,
 	}
 }
@@ -115,12 +118,7 @@ func (gf *gracefulImpl) Shutdown() {
 	if infra.DEBUG {
 		log.Debug("[glacier] graceful closing...")
 	}
-	_ = gf.signalSelf(os.Interrupt)
-}
-
-func (gf *gracefulImpl) signalSelf(sig os.Signal) error {
-	gf.signalChan <- sig
-	return nil
+	gf.shutdownChan <- struct{}{}
 }
 
 func (gf *gracefulImpl) shutdown() {
@@ -259,10 +257,18 @@ func (gf *gracefulImpl) Start() error {
 	signals := make([]os.Signal, 0)
 	signals = append(signals, gf.reloadSignals...)
 	signals = append(signals, gf.shutdownSignals...)
-	gf.signalHandler(gf.signalChan, signals)
+	// signal.Notify relays all signals when no signal is provided
+	if len(signals) > 0 {
+		gf.signalHandler(gf.signalChan, signals)
+	}
 
 	for {
-		sig := <-gf.signalChan
+		var sig os.Signal
+		select {
+		case <-gf.shutdownChan:
+			goto FINAL
+		case sig = <-gf.signalChan:
+		}
 
 		for _, s := range gf.shutdownSignals {
 			if s == sig {
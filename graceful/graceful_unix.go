@@ -10,6 +10,12 @@ import (
 	"time"
 )
 
+// DefaultReloadSignals 默认触发 reload 的信号
+var DefaultReloadSignals = []os.Signal{syscall.SIGUSR2}
+
+// DefaultShutdownSignals 默认触发优雅停机的信号
+var DefaultShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP, syscall.SIGQUIT}
+
 func NewWithDefault(perHandlerTimeout time.Duration) infra.Graceful {
-	return NewWithSignal([]os.Signal{syscall.SIGUSR2}, []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP, syscall.SIGQUIT}, perHandlerTimeout)
+	return NewWithSignal(DefaultReloadSignals, DefaultShutdownSignals, perHandlerTimeout)
 }
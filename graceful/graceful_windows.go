@@ -9,6 +9,12 @@ import (
 	"time"
 )
 
+// DefaultReloadSignals 默认触发 reload 的信号
+var DefaultReloadSignals = []os.Signal{}
+
+// DefaultShutdownSignals 默认触发优雅停机的信号
+var DefaultShutdownSignals = []os.Signal{os.Interrupt}
+
 func NewWithDefault(perHandlerTimeout time.Duration) infra.Graceful {
-	return NewWithSignal([]os.Signal{}, []os.Signal{os.Interrupt}, perHandlerTimeout)
+	return NewWithSignal(DefaultReloadSignals, DefaultShutdownSignals, perHandlerTimeout)
 }
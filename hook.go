@@ -20,6 +20,19 @@ func (impl *framework) Init(f func(c infra.FlagContext) error) infra.Glacier {
 	return impl
 }
 
+// BeforeServerStart call a function after all providers booted, and before daemon providers and services start,
+// the hooks are called in registration order, an error returned by hook aborts the startup
+func (impl *framework) BeforeServerStart(f func(resolver infra.Resolver) error) {
+	impl.lock.Lock()
+	defer impl.lock.Unlock()
+
+	if impl.status == Started {
+		panic(fmt.Errorf("[glacier] can not call BeforeServerStart since server has been started"))
+	}
+
+	impl.beforeServerStartHooks = append(impl.beforeServerStartHooks, f)
+}
+
 // OnServerReady call a function on server ready
 func (impl *framework) OnServerReady(ffs ...interface{}) {
 	impl.lock.Lock()
@@ -39,6 +52,22 @@ func (impl *framework) OnServerReady(ffs ...interface{}) {
 	}
 }
 
+// OnServerStop call a function after all modules stopped, the functions are called in LIFO order,
+// so the resources initialized earlier are released later. Arguments of the function are resolved from container
+func (impl *framework) OnServerStop(ffs ...interface{}) {
+	impl.lock.Lock()
+	defer impl.lock.Unlock()
+
+	for _, f := range ffs {
+		fn := newNamedFunc(f)
+		if reflect.TypeOf(f).Kind() != reflect.Func {
+			panic(fmt.Errorf("[glacier] argument for OnServerStop [%s] must be a callable function", fn.name))
+		}
+
+		impl.onServerStopHooks = append(impl.onServerStopHooks, fn)
+	}
+}
+
 // BeforeServerStop set a hook func executed before server stop
 func (impl *framework) BeforeServerStop(f func(cc infra.Resolver) error) infra.Glacier {
 	impl.beforeServerStop = f
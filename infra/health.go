@@ -0,0 +1,18 @@
+package infra
+
+import "context"
+
+// HealthChecker is a component which reports its health to the health check endpoint
+type HealthChecker interface {
+	// Name of the component, it's used as the key in health check response
+	Name() string
+	// CheckHealth return an error if the component is unhealthy
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthCheck is the config of the built-in health check endpoint, it's bound to container by
+// app.WithHealthCheck, and served by the web module
+type HealthCheck struct {
+	Path     string
+	Checkers []func(resolver Resolver) HealthChecker
+}
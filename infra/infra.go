@@ -3,8 +3,11 @@ package infra
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
+	"os"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/mylxsw/go-ioc"
@@ -24,6 +27,22 @@ var (
 	PrintGraph = false
 )
 
+// Environment is the environment the application running in, like prod, staging
+type Environment string
+
+// ServiceName is the name of the service, it's used to tag the metrics and logs
+type ServiceName string
+
+// DefaultLabels create labels from the Environment (env) and ServiceName (service) in container if they are bound,
+// they are used by modules like scheduler and web to tag their metrics
+func DefaultLabels(resolver Resolver) map[string]string {
+	labels := make(map[string]string)
+	_ = resolver.Resolve(func(env Environment) { labels["env"] = string(env) })
+	_ = resolver.Resolve(func(service ServiceName) { labels["service"] = string(service) })
+
+	return labels
+}
+
 type Graceful interface {
 	AddReloadHandler(h func())
 	AddShutdownHandler(h func())
@@ -131,6 +150,18 @@ type Logger interface {
 	Criticalf(format string, v ...interface{})
 }
 
+// Fields is the structured fields of a log entry
+type Fields map[string]interface{}
+
+// StructuredLogger is a Logger supporting structured fields, e.g. an adapter of zap or zerolog which writes
+// JSON logs. When the logger set by SetLogger implements it, glacier logs with fields like job name, duration
+// and error, otherwise the fields are appended to the message as key=value pairs
+type StructuredLogger interface {
+	Logger
+	// WithFields return a Logger which attaches the fields to every log entry
+	WithFields(fields Fields) Logger
+}
+
 type Glacier interface {
 	SetLogger(logger Logger) Glacier
 
@@ -147,9 +178,17 @@ type Glacier interface {
 
 	// Graceful 设置优雅停机实现
 	Graceful(builder func() Graceful) Glacier
+	// WithShutdownSignals 设置触发优雅停机的信号，使用 Graceful 设置了自定义实现时无效
+	WithShutdownSignals(sigs ...os.Signal) Glacier
+	// WithReloadSignals 设置触发 reload（执行 Graceful.AddReloadHandler 注册的处理函数）的信号，使用 Graceful 设置了自定义实现时无效
+	WithReloadSignals(sigs ...os.Signal) Glacier
 
+	// BeforeServerStart call a function before the daemon providers and services start, an error aborts the startup
+	BeforeServerStart(f func(resolver Resolver) error)
 	// OnServerReady call a function a server ready
 	OnServerReady(ffs ...interface{})
+	// OnServerStop call a function after all modules stopped, in LIFO order
+	OnServerStop(ffs ...interface{})
 
 	// Start 应用入口
 	Start(cliCtx FlagContext) error
@@ -159,8 +198,15 @@ type Glacier interface {
 	BeforeServerStop(f func(resolver Resolver) error) Glacier
 	PreBind(fn func(binder Binder)) Glacier
 
+	// Singleton 单例绑定，实例在第一次被使用时创建，之后所有的使用者（包括并发执行的定时任务和 http 请求）共享同一个实例
 	Singleton(ins ...interface{}) Glacier
+	// Prototype 原型绑定，每次被使用时都会创建新的实例
 	Prototype(ins ...interface{}) Glacier
+	// SingletonWithKey 使用 key 进行单例绑定，用于同一类型存在多个实例的场景（比如主库和从库的 *sql.DB），
+	// 通过 ResolveWithKey 获取
+	SingletonWithKey(key string, ins interface{}) Glacier
+	// PrototypeWithKey 使用 key 进行原型绑定
+	PrototypeWithKey(key string, ins interface{}) Glacier
 	Resolve(resolver interface{}) error
 	MustResolve(resolver interface{})
 	Container() Container
@@ -173,8 +219,15 @@ type Binder ioc.Binder
 type Resolver ioc.Resolver
 
 type Hook interface {
+	// BeforeServerStart call a function after all providers booted, but before the daemon providers (http server,
+	// scheduler, etc.) and services start, an error returned aborts the startup. The order of hooks is
+	// BeforeServerStart -> modules start -> OnServerReady
+	BeforeServerStart(f func(resolver Resolver) error)
 	// OnServerReady call a function a server ready
 	OnServerReady(ffs ...interface{})
+	// OnServerStop call a function after all modules (http server, scheduler, etc.) stopped,
+	// the functions are called in LIFO order
+	OnServerStop(ffs ...interface{})
 }
 
 func WithCondition(init interface{}, onCondition interface{}) ioc.Conditional {
@@ -190,3 +243,42 @@ func Autowire[T any](resolver Resolver, obj T) T {
 	resolver.MustAutoWire(obj)
 	return obj
 }
+
+// BindingKey is the key of keyed bindings, the container only accepts keys of pointer (or struct, interface)
+// kinds for bindings created by functions, so keys are interned pointers, the same name always gets the same key
+type BindingKey struct {
+	name string
+}
+
+var bindingKeys sync.Map
+
+// KeyOf get the BindingKey of name, it can be used with Binder.SingletonWithKey and Binder.PrototypeWithKey in providers
+//
+//	binder.MustSingletonWithKey(infra.KeyOf("replica"), func(conf *Config) (*sql.DB, error) {...})
+func KeyOf(name string) *BindingKey {
+	key, _ := bindingKeys.LoadOrStore(name, &BindingKey{name: name})
+	return key.(*BindingKey)
+}
+
+func (key *BindingKey) String() string {
+	return key.name
+}
+
+// ResolveWithKey get the instance bound with key (by Glacier.SingletonWithKey, Glacier.PrototypeWithKey, or Binder with KeyOf(key))
+// as type T. Go does not support tags for function arguments, so handlers which need a keyed instance can accept an
+// infra.Resolver argument and call this function
+func ResolveWithKey[T any](resolver Resolver, key string) (T, error) {
+	var res T
+
+	ins, err := resolver.Get(KeyOf(key))
+	if err != nil {
+		return res, err
+	}
+
+	res, ok := ins.(T)
+	if !ok {
+		return res, fmt.Errorf("[glacier] instance with key [%s] is %T, not %s", key, ins, reflect.TypeOf((*T)(nil)).Elem())
+	}
+
+	return res, nil
+}
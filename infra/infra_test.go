@@ -0,0 +1,37 @@
+package infra_test
+
+import (
+	"testing"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/go-ioc"
+)
+
+type database struct {
+	name string
+}
+
+func TestResolveWithKey(t *testing.T) {
+	cc := ioc.New()
+	cc.MustSingletonWithKey(infra.KeyOf("primary"), func() *database { return &database{name: "primary"} })
+	cc.MustSingletonWithKey(infra.KeyOf("replica"), func() *database { return &database{name: "replica"} })
+
+	for _, key := range []string{"primary", "replica"} {
+		db, err := infra.ResolveWithKey[*database](cc, key)
+		if err != nil || db.name != key {
+			t.Errorf("instance with key [%s] should be resolved, got %v, %v", key, db, err)
+		}
+	}
+
+	if infra.KeyOf("replica") != infra.KeyOf("replica") {
+		t.Error("the same name should always get the same key")
+	}
+
+	if _, err := infra.ResolveWithKey[string](cc, "replica"); err == nil {
+		t.Error("instance of another type should be rejected")
+	}
+
+	if _, err := infra.ResolveWithKey[*database](cc, "missing"); err == nil {
+		t.Error("missing key should be rejected")
+	}
+}
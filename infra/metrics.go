@@ -0,0 +1,16 @@
+package infra
+
+import "io"
+
+// MetricsCollector is a component which exports its metrics at the metrics endpoint
+type MetricsCollector interface {
+	// CollectMetrics write metric families in OpenMetrics text format, without the "# EOF" terminator
+	CollectMetrics(w io.Writer) error
+}
+
+// Metrics is the config of the built-in Prometheus metrics endpoint, it's bound to container by
+// app.WithPrometheus, and served by the web module
+type Metrics struct {
+	Path       string
+	Collectors []func(resolver Resolver) MetricsCollector
+}
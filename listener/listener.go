@@ -2,7 +2,9 @@ package listener
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"os"
 
 	"github.com/mylxsw/glacier/infra"
 )
@@ -52,3 +54,38 @@ func Exist(listener net.Listener) infra.ListenerBuilder {
 func (e existedBuilder) Build(infra.Resolver) (net.Listener, error) {
 	return e.listener, nil
 }
+
+// unixBuilder 基于 Unix Domain Socket 的 listener 构建器
+type unixBuilder struct {
+	path           string
+	removeExisting bool
+}
+
+// Unix 创建监听 Unix Domain Socket 的 listener 构建器，可以通过文件权限来限制访问
+// 如果 socket 文件已经存在，removeExisting 为 true 时会先删除该文件（只会删除 socket 类型的文件），否则返回错误
+// listener 关闭时（服务停止时）socket 文件会被自动删除
+func Unix(path string, removeExisting bool) infra.ListenerBuilder {
+	return unixBuilder{path: path, removeExisting: removeExisting}
+}
+
+func (e unixBuilder) Build(infra.Resolver) (net.Listener, error) {
+	if e.path == "" {
+		return nil, errors.New("unix socket path is required")
+	}
+
+	if stat, err := os.Lstat(e.path); err == nil {
+		if !e.removeExisting {
+			return nil, fmt.Errorf("unix socket %s already exists", e.path)
+		}
+
+		if stat.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s already exists and is not a unix socket", e.path)
+		}
+
+		if err := os.Remove(e.path); err != nil {
+			return nil, fmt.Errorf("remove existing unix socket %s failed: %w", e.path, err)
+		}
+	}
+
+	return net.Listen("unix", e.path)
+}
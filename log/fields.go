@@ -0,0 +1,141 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mylxsw/glacier/infra"
+)
+
+var defaultFields = infra.Fields{}
+
+// AddDefaultFields add fields attached to every log entry created by WithFields, like the environment and
+// service name of application, the fields passed to WithFields take precedence over them
+func AddDefaultFields(fields infra.Fields) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	merged := make(infra.Fields, len(defaultFields)+len(fields))
+	for k, v := range defaultFields {
+		merged[k] = v
+	}
+
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	defaultFields = merged
+}
+
+// WithFields return a logger which attaches the fields to every log entry, fields are passed to the default
+// logger if it's an infra.StructuredLogger, otherwise they are appended to the message as key=value pairs.
+// The default fields (see AddDefaultFields) are attached as well
+func WithFields(fields infra.Fields) infra.Logger {
+	lock.RLock()
+	logger, defaults := defaultLogger, defaultFields
+	lock.RUnlock()
+
+	if len(defaults) > 0 {
+		merged := make(infra.Fields, len(defaults)+len(fields))
+		for k, v := range defaults {
+			merged[k] = v
+		}
+
+		for k, v := range fields {
+			merged[k] = v
+		}
+
+		fields = merged
+	}
+
+	if sl, ok := logger.(infra.StructuredLogger); ok {
+		return sl.WithFields(fields)
+	}
+
+	return fieldsLogger{logger: logger, suffix: formatFields(fields)}
+}
+
+// formatFields format fields as sorted key=value pairs, values with spaces are quoted, and values with
+// line breaks (like stack) are put at the end on new lines
+func formatFields(fields infra.Fields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs, blocks []string
+	for _, k := range keys {
+		val := fmt.Sprintf("%v", fields[k])
+		switch {
+		case strings.Contains(val, "\n"):
+			blocks = append(blocks, k+":\n"+val)
+		case val == "" || strings.ContainsAny(val, " \t\"="):
+			pairs = append(pairs, fmt.Sprintf("%s=%q", k, val))
+		default:
+			pairs = append(pairs, k+"="+val)
+		}
+	}
+
+	res := strings.Join(pairs, " ")
+	if len(blocks) > 0 {
+		res += "\n" + strings.Join(blocks, "\n")
+	}
+
+	return res
+}
+
+// fieldsLogger is a logger which appends formatted fields to messages of a non-structured logger
+type fieldsLogger struct {
+	logger infra.Logger
+	suffix string
+}
+
+func (l fieldsLogger) with(msg string) string {
+	if l.suffix == "" {
+		return msg
+	}
+
+	return msg + " " + l.suffix
+}
+
+func (l fieldsLogger) Debug(v ...interface{}) {
+	l.logger.Debug(l.with(fmt.Sprint(v...)))
+}
+
+func (l fieldsLogger) Debugf(format string, v ...interface{}) {
+	l.logger.Debug(l.with(fmt.Sprintf(format, v...)))
+}
+
+func (l fieldsLogger) Info(v ...interface{}) {
+	l.logger.Info(l.with(fmt.Sprint(v...)))
+}
+
+func (l fieldsLogger) Infof(format string, v ...interface{}) {
+	l.logger.Info(l.with(fmt.Sprintf(format, v...)))
+}
+
+func (l fieldsLogger) Error(v ...interface{}) {
+	l.logger.Error(l.with(fmt.Sprint(v...)))
+}
+
+func (l fieldsLogger) Errorf(format string, v ...interface{}) {
+	l.logger.Error(l.with(fmt.Sprintf(format, v...)))
+}
+
+func (l fieldsLogger) Warning(v ...interface{}) {
+	l.logger.Warning(l.with(fmt.Sprint(v...)))
+}
+
+func (l fieldsLogger) Warningf(format string, v ...interface{}) {
+	l.logger.Warning(l.with(fmt.Sprintf(format, v...)))
+}
+
+func (l fieldsLogger) Critical(v ...interface{}) {
+	l.logger.Critical(l.with(fmt.Sprint(v...)))
+}
+
+func (l fieldsLogger) Criticalf(format string, v ...interface{}) {
+	l.logger.Critical(l.with(fmt.Sprintf(format, v...)))
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/mylxsw/glacier/infra"
+)
+
+type fieldsRecorder struct {
+	infra.Logger
+	fields *infra.Fields
+}
+
+func (r fieldsRecorder) WithFields(fields infra.Fields) infra.Logger {
+	*r.fields = fields
+	return r
+}
+
+func TestDefaultFields(t *testing.T) {
+	var fields infra.Fields
+	SetDefaultLogger(fieldsRecorder{Logger: StdLogger(), fields: &fields})
+	defer SetDefaultLogger(StdLogger())
+
+	AddDefaultFields(infra.Fields{"env": "prod", "service": "billing"})
+	defer func() { defaultFields = infra.Fields{} }()
+
+	WithFields(infra.Fields{"job": "sync", "service": "override"})
+
+	expected := infra.Fields{"env": "prod", "service": "override", "job": "sync"}
+	if len(fields) != len(expected) {
+		t.Fatalf("expect %v, got %v", expected, fields)
+	}
+
+	for k, v := range expected {
+		if fields[k] != v {
+			t.Errorf("expect %s=%v, got %v", k, v, fields[k])
+		}
+	}
+}
+
+func TestFormatFields(t *testing.T) {
+	cases := []struct {
+		fields   infra.Fields
+		expected string
+	}{
+		{infra.Fields{}, ""},
+		{infra.Fields{"job": "sync", "attempt": 2}, "attempt=2 job=sync"},
+		{infra.Fields{"error": "connection refused", "empty": ""}, `empty="" error="connection refused"`},
+		{infra.Fields{"msg": `say "hi"`, "kv": "a=b"}, `kv="a=b" msg="say \"hi\""`},
+		{infra.Fields{"job": "sync", "stack": "goroutine 1\nmain.go:10"}, "job=sync\nstack:\ngoroutine 1\nmain.go:10"},
+	}
+
+	for _, c := range cases {
+		if res := formatFields(c.fields); res != c.expected {
+			t.Errorf("expect %q, got %q", c.expected, res)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/robfig/cron/v3"
+)
+
+// CronAdapter provides the AddFunc/AddJob/Remove API of *cron.Cron on top of Scheduler, it helps to
+// migrate the code which adds jobs to *cron.Cron directly: jobs added through the adapter are tracked
+// by the scheduler, so they can be paused, inspected, recovered from panics and protected by locks.
+//
+//	adapter := scheduler.NewCronAdapter(sc, "legacy")
+//	id, err := adapter.AddFunc("@every 10s", func() { ... })
+//
+// The jobs are named as "{prefix}-{seq}", the returned entry ID is the ID of the job when it's added,
+// it changes when the job is paused and continued, use the job name to refer to the job afterwards.
+type CronAdapter struct {
+	lock      sync.Mutex
+	scheduler Scheduler
+	prefix    string
+	seq       int
+	names     map[cron.EntryID]string
+}
+
+// NewCronAdapter create a CronAdapter, prefix is used to generate job names
+func NewCronAdapter(s Scheduler, prefix string) *CronAdapter {
+	if prefix == "" {
+		prefix = "cron"
+	}
+
+	return &CronAdapter{scheduler: s, prefix: prefix, names: make(map[cron.EntryID]string)}
+}
+
+// AddFunc add a func to the scheduler
+func (adapter *CronAdapter) AddFunc(spec string, cmd func()) (cron.EntryID, error) {
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+
+	adapter.seq++
+	name := fmt.Sprintf("%s-%d", adapter.prefix, adapter.seq)
+	if err := adapter.scheduler.Add(name, spec, cmd); err != nil {
+		return 0, err
+	}
+
+	job, err := adapter.scheduler.Info(name)
+	if err != nil {
+		return 0, err
+	}
+
+	adapter.names[job.ID] = name
+	return job.ID, nil
+}
+
+// AddJob add a cron.Job to the scheduler
+func (adapter *CronAdapter) AddJob(spec string, cmd cron.Job) (cron.EntryID, error) {
+	return adapter.AddFunc(spec, cmd.Run)
+}
+
+// Name return the job name for the entry ID returned by AddFunc or AddJob
+func (adapter *CronAdapter) Name(id cron.EntryID) (string, bool) {
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+
+	name, ok := adapter.names[id]
+	return name, ok
+}
+
+// Remove remove the job with the entry ID returned by AddFunc or AddJob
+func (adapter *CronAdapter) Remove(id cron.EntryID) {
+	adapter.lock.Lock()
+	defer adapter.lock.Unlock()
+
+	name, ok := adapter.names[id]
+	if !ok {
+		return
+	}
+
+	delete(adapter.names, id)
+	_ = adapter.scheduler.Remove(name)
+}
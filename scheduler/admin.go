@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/mylxsw/glacier/log"
+	"github.com/pkg/errors"
+)
+
+// AdminHandler create a http.Handler which exposes the scheduler as a set of REST endpoints
+//
+//	GET    /jobs                 list all jobs
+//	GET    /jobs/{name}          get job info
+//	GET    /jobs/{name}/stats    get execution statistics of a job
+//	GET    /jobs/{name}/history  get the latest executions of a job, query: limit, see Scheduler.History
+//	DELETE /jobs/{name}          remove a job
+//	POST   /jobs/{name}/pause    pause a job
+//	POST   /jobs/{name}/continue continue a paused job
+//	POST   /jobs/{name}/trigger  run a job immediately
+//	PUT    /jobs/{name}/plan     change the plan of a job, body: {"plan": "@every 1m"}
+//	GET    /metrics              stats of scheduler in OpenMetrics text format
+//
+// The handler does not perform any authentication, wrap it with your own auth middleware,
+// and use http.StripPrefix when mounting it under a sub path.
+func AdminHandler(s Scheduler) http.Handler {
+	router := mux.NewRouter()
+
+	router.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminResponse(w, http.StatusOK, s.List())
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/jobs/{name}", func(w http.ResponseWriter, r *http.Request) {
+		job, err := s.Info(mux.Vars(r)["name"])
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+
+		writeAdminResponse(w, http.StatusOK, job)
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/jobs/{name}/stats", func(w http.ResponseWriter, r *http.Request) {
+		stats, err := s.JobStats(mux.Vars(r)["name"])
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+
+		writeAdminResponse(w, http.StatusOK, stats)
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/jobs/{name}/history", func(w http.ResponseWriter, r *http.Request) {
+		var limit int
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				writeAdminResponse(w, http.StatusBadRequest, adminMessage{Error: "invalid request, limit must be a non-negative integer"})
+				return
+			}
+
+			limit = parsed
+		}
+
+		history, err := s.History(mux.Vars(r)["name"], limit)
+		if err != nil {
+			writeAdminError(w, err)
+			return
+		}
+
+		writeAdminResponse(w, http.StatusOK, history)
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/jobs/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Remove(mux.Vars(r)["name"]); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+
+		writeAdminResponse(w, http.StatusOK, adminMessage{Message: "removed"})
+	}).Methods(http.MethodDelete)
+
+	router.HandleFunc("/jobs/{name}/pause", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Pause(mux.Vars(r)["name"]); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+
+		writeAdminResponse(w, http.StatusOK, adminMessage{Message: "paused"})
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/jobs/{name}/continue", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Continue(mux.Vars(r)["name"]); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+
+		writeAdminResponse(w, http.StatusOK, adminMessage{Message: "continued"})
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/jobs/{name}/trigger", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Trigger(mux.Vars(r)["name"]); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+
+		writeAdminResponse(w, http.StatusAccepted, adminMessage{Message: "triggered"})
+	}).Methods(http.MethodPost)
+
+	router.HandleFunc("/jobs/{name}/plan", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Plan string `json:"plan"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Plan == "" {
+			writeAdminResponse(w, http.StatusBadRequest, adminMessage{Error: "invalid request, plan is required"})
+			return
+		}
+
+		if err := s.UpdatePlan(mux.Vars(r)["name"], req.Plan); err != nil {
+			writeAdminError(w, err)
+			return
+		}
+
+		writeAdminResponse(w, http.StatusOK, adminMessage{Message: "updated"})
+	}).Methods(http.MethodPut)
+
+	router.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", MetricsContentType)
+		if err := s.WriteMetrics(w); err != nil {
+			log.Errorf("[glacier] write scheduler metrics failed: %v", err)
+		}
+	}).Methods(http.MethodGet)
+
+	return router
+}
+
+type adminMessage struct {
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if errors.Is(err, ErrJobNotFound) {
+		code = http.StatusNotFound
+	} else if errors.Is(err, ErrInternalJob) {
+		code = http.StatusForbidden
+	} else if errors.Is(err, ErrHistoryNotSupported) {
+		code = http.StatusNotImplemented
+	}
+
+	writeAdminResponse(w, code, adminMessage{Error: err.Error()})
+}
+
+func writeAdminResponse(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Errorf("[glacier] write scheduler admin response failed: %v", err)
+	}
+}
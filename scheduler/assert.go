@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TestingT is the subset of testing.T used by AssertJobs
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// DiffJobs compare the jobs in scheduler with expected jobs (name => plan), and return the differences.
+// Only the expected jobs are checked, jobs not in expected are ignored. An empty result means all
+// expected jobs are registered with the expected plans.
+func DiffJobs(s Scheduler, expected map[string]string) []string {
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	diff := make([]string, 0)
+	for _, name := range names {
+		job, err := s.Info(name)
+		if err != nil {
+			diff = append(diff, fmt.Sprintf("- %s (%s): not registered", name, expected[name]))
+			continue
+		}
+
+		if job.Plan != expected[name] {
+			diff = append(diff, fmt.Sprintf("~ %s: expected plan [%s], got [%s]", name, expected[name], job.Plan))
+		}
+	}
+
+	return diff
+}
+
+// AssertJobs assert that all expected jobs (name => plan) are registered in scheduler with the expected plans
+//
+//	scheduler.AssertJobs(t, s, map[string]string{"sync-users": "@every 1m"})
+func AssertJobs(t TestingT, s Scheduler, expected map[string]string) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	diff := DiffJobs(s, expected)
+	if len(diff) == 0 {
+		return true
+	}
+
+	t.Errorf("jobs registration mismatch:\n%s", strings.Join(diff, "\n"))
+	return false
+}
@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxNameAttempts is the max number of names generated for a job before AddAuto gives up
+const maxNameAttempts = 10
+
+func (c *schedulerImpl) SetNameGenerator(generator func() string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.nameGenerator = generator
+}
+
+func (c *schedulerImpl) AddAuto(plan string, handler interface{}, options ...JobOption) (string, error) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	for i := 0; i < maxNameAttempts; i++ {
+		name := c.generateName()
+		if _, existed := c.jobs[name]; existed {
+			continue
+		}
+
+		if _, err := c.addJob(name, plan, handler, options...); err != nil {
+			return "", err
+		}
+
+		return name, nil
+	}
+
+	return "", errors.New("[glacier] can not generate a unique job name")
+}
+
+// generateName generate a job name by the name generator, or "auto-{seq}" if no generator is set,
+// the caller must hold the write lock
+func (c *schedulerImpl) generateName() string {
+	if c.nameGenerator != nil {
+		return c.nameGenerator()
+	}
+
+	c.autoSeq++
+	return fmt.Sprintf("auto-%d", c.autoSeq)
+}
@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+func (c *schedulerImpl) OnBeforeRun(fn func(name string)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.beforeRunCallbacks = append(c.beforeRunCallbacks, fn)
+}
+
+func (c *schedulerImpl) OnAfterRun(fn func(name string, err error, d time.Duration)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.afterRunCallbacks = append(c.afterRunCallbacks, fn)
+}
+
+// beforeRun invoke all callbacks registered by OnBeforeRun
+func (c *schedulerImpl) beforeRun(name string) {
+	c.lock.RLock()
+	callbacks := c.beforeRunCallbacks
+	c.lock.RUnlock()
+
+	for _, cb := range callbacks {
+		safeCallback(name, func() { cb(name) })
+	}
+}
+
+// afterRun invoke all callbacks registered by OnAfterRun
+func (c *schedulerImpl) afterRun(name string, err error, d time.Duration) {
+	c.lock.RLock()
+	callbacks := c.afterRunCallbacks
+	c.lock.RUnlock()
+
+	for _, cb := range callbacks {
+		safeCallback(name, func() { cb(name, err, d) })
+	}
+}
+
+// safeCallback call fn, and recover from its panic, so that callbacks never break the job execution
+func safeCallback(name string, fn func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.WithFields(infra.Fields{"job": name, "error": err}).Errorf("[glacier] callback for cron job [%s] panic: %v", name, err)
+		}
+	}()
+
+	fn()
+}
@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is used by scheduler to get the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// waitClock is a Clock which can wait for a duration, the scheduler waits with it when it's implemented
+type waitClock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// after wait for d with the clock of scheduler, real time is used if the clock can not wait
+func (c *schedulerImpl) after(d time.Duration) <-chan time.Time {
+	if clock, ok := c.clock.(waitClock); ok {
+		return clock.After(d)
+	}
+
+	return time.After(d)
+}
+
+// FakeClock is a Clock whose time only changes when Set or Advance is called
+type FakeClock struct {
+	lock sync.RWMutex
+	now  time.Time
+}
+
+// NewFakeClock create a new FakeClock starts at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.now
+}
+
+// Set change the current time of the clock
+func (f *FakeClock) Set(now time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.now = now
+}
+
+// After move the clock forward by d, and return a channel which is already fired, so the waits of
+// scheduler (like the backoff of WithRetry) take no real time in tests
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	f.Advance(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- f.Now()
+	return ch
+}
+
+// Advance move the clock forward by d
+func (f *FakeClock) Advance(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.now = f.now.Add(d)
+}
@@ -0,0 +1,45 @@
+package scheduler
+
+func (c *schedulerImpl) SetTagConcurrency(tag string, limit int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if limit <= 0 {
+		delete(c.tagSemaphores, tag)
+		return
+	}
+
+	c.tagSemaphores[tag] = make(chan struct{}, limit)
+}
+
+// acquireTagSemaphores try to acquire the semaphores of all tags with concurrency limit for job,
+// if any of them is not available, all acquired semaphores will be released and false is returned
+func (c *schedulerImpl) acquireTagSemaphores(job *Job) (release func(), ok bool) {
+	c.lock.RLock()
+	semaphores := make([]chan struct{}, 0)
+	for _, tag := range job.Tags {
+		if sem, exist := c.tagSemaphores[tag]; exist {
+			semaphores = append(semaphores, sem)
+		}
+	}
+	c.lock.RUnlock()
+
+	acquired := make([]chan struct{}, 0, len(semaphores))
+	release = func() {
+		for _, sem := range acquired {
+			<-sem
+		}
+	}
+
+	for _, sem := range semaphores {
+		select {
+		case sem <- struct{}{}:
+			acquired = append(acquired, sem)
+		default:
+			release()
+			return nil, false
+		}
+	}
+
+	return release, true
+}
@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+)
+
+func (c *schedulerImpl) EntryCount() int {
+	return len(c.cr.Entries())
+}
+
+// CheckConsistency verify that every active job has exactly one cron entry, and there are no
+// entries besides the active jobs (including internal tasks)
+func (c *schedulerImpl) CheckConsistency() error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	expected := make(map[cron.EntryID]string)
+	for _, job := range c.jobs {
+		if !job.Paused {
+			expected[job.ID] = job.Name
+		}
+	}
+
+	problems := make([]string, 0)
+	actual := make(map[cron.EntryID]bool)
+	for _, entry := range c.cr.Entries() {
+		actual[entry.ID] = true
+		if _, ok := expected[entry.ID]; !ok {
+			problems = append(problems, fmt.Sprintf("leaked entry %d", entry.ID))
+		}
+	}
+
+	for id, name := range expected {
+		if !actual[id] {
+			problems = append(problems, fmt.Sprintf("missing entry %d for %s", id, name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("[glacier] scheduler is inconsistent: %s", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+type contextKey int
+
+const (
+	localeKey contextKey = iota
+	runIDKey
+)
+
+// runContext create the context for a run of job, it's provided to the handler as context.Context,
+// and shared by all attempts of the run (see WithRetry).
+//
+// The context is derived from the base context of scheduler, which is cancelled when the scheduler
+// is stopped, and carries the ID of the execution (see RunIDFromContext). It's also cancelled after timeout
+// (see WithTimeout) unless the timeout is zero, or when the distributed lock of job is lost, so the handler
+// should stop when any of them happens. The cancel function must be called after the run.
+func (c *schedulerImpl) runContext(job *Job, runID string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	c.lock.RLock()
+	ctx, locked := c.baseCtx, job.lockManager != nil
+	c.lock.RUnlock()
+
+	ctx = context.WithValue(ctx, runIDKey, runID)
+
+	if job.options.locale != "" {
+		ctx = context.WithValue(ctx, localeKey, job.options.locale)
+	}
+
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	// the run is cancelled when the distributed lock of job is lost, see OnLockLost
+	if locked {
+		cancel = c.locks.track(job.Name, cancel)
+	}
+
+	return ctx, cancel
+}
+
+// resetBaseContext create a new base context for runs if the current one is cancelled by Stop
+func (c *schedulerImpl) resetBaseContext() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.baseCtx == nil || c.baseCtx.Err() != nil {
+		c.baseCtx, c.cancelBaseCtx = context.WithCancel(context.Background())
+	}
+}
+
+// cancelRuns cancel the contexts of all runs in progress, it's called when the scheduler is stopped
+func (c *schedulerImpl) cancelRuns() {
+	c.lock.RLock()
+	cancel := c.cancelBaseCtx
+	c.lock.RUnlock()
+
+	cancel()
+}
+
+// LocaleFromContext get the locale of job set by WithLocale from the run context
+func LocaleFromContext(ctx context.Context) (string, bool) {
+	locale, ok := ctx.Value(localeKey).(string)
+	return locale, ok
+}
@@ -3,8 +3,12 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"io"
 	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mylxsw/glacier/log"
@@ -17,34 +21,199 @@ import (
 // JobCreator is a creator for cron job
 type JobCreator interface {
 	// Add a cron job
-	Add(name string, plan string, handler interface{}) error
+	Add(name string, plan string, handler interface{}, options ...JobOption) error
+	// AddAndReturnID add a cron job, and return the id of its entry in the underlying cron, see InfoByID
+	AddAndReturnID(name string, plan string, handler interface{}, options ...JobOption) (cron.EntryID, error)
+	// AddOrReplace add a cron job, or replace the job with the same name atomically, the paused state of
+	// the replaced job is kept
+	AddOrReplace(name string, plan string, handler interface{}, options ...JobOption) error
 	// AddAndRunOnServerReady add a cron job, and trigger it immediately when server is ready
-	AddAndRunOnServerReady(name string, plan string, handler interface{}) error
+	AddAndRunOnServerReady(name string, plan string, handler interface{}, options ...JobOption) error
 
 	// MustAdd add a cron job
-	MustAdd(name string, plan string, handler interface{})
+	MustAdd(name string, plan string, handler interface{}, options ...JobOption)
 	// MustAddAndRunOnServerReady add a cron job, and trigger it immediately when server is ready
-	MustAddAndRunOnServerReady(name string, plan string, handler interface{})
+	MustAddAndRunOnServerReady(name string, plan string, handler interface{}, options ...JobOption)
+
+	// AddDynamic add a job whose next execution time is computed by next after each execution, last is
+	// the scheduled time of last execution (zero before the first one), the times passed while the job is
+	// paused are not executions. When next returns an error, it's retried with backoff
+	AddDynamic(name string, next func(last time.Time) (time.Time, error), handler interface{}, options ...JobOption) error
+	// RunAt add a one-shot job which is executed once at t, and removed from scheduler after execution.
+	// If t is in the past (or passed while the job is paused), the job is executed as soon as possible.
+	// The execution skipped on this node is retried, unless it's skipped because another node holds the lock
+	RunAt(name string, t time.Time, handler interface{}, options ...JobOption) error
+
+	// RegisterStruct add all fields with `cron` tag in struct v as cron jobs
+	RegisterStruct(v interface{}) error
+
+	// Namespace return a JobCreator which adds jobs with name prefixed by namespace, like `namespace.name`
+	Namespace(namespace string) JobCreator
 }
 
 // Scheduler is a manager object to manage cron jobs
+//
+// Scheduler is registered to the container by Provider, so handlers can declare a Scheduler argument
+// to add, remove or pause jobs. Handlers are executed without holding the scheduler lock, it's safe
+// to call any method of Scheduler inside a handler, including removing the job itself.
 type Scheduler interface {
 	JobCreator
+	// AddAuto add a job with a generated unique name, and return the name
+	AddAuto(plan string, handler interface{}, options ...JobOption) (string, error)
+	// SetNameGenerator set the generator of job names used by AddAuto, "auto-{seq}" is used by default
+	SetNameGenerator(generator func() string)
+
 	// Remove remove a cron job
 	Remove(name string) error
 	// Pause set job status to paused
 	Pause(name string) error
 	// Continue set job status to continue
 	Continue(name string) error
+	// PauseUntil pause the job now, and continue it automatically at until
+	PauseUntil(name string, until time.Time) error
+	// PauseByTag pause all jobs with the tag
+	PauseByTag(tag string) error
+	// ContinueByTag continue all jobs with the tag
+	ContinueByTag(tag string) error
+	// UpdatePlan change the plan of job in place, the paused job keeps paused and uses the new plan when continued
+	UpdatePlan(name string, plan string) error
+	// PauseAll pause all jobs added by users, the jobs already paused are not changed
+	PauseAll() error
+	// ContinueAll continue the jobs paused by PauseAll, the jobs paused individually keep paused
+	ContinueAll() error
+	// Trigger run the job immediately in a new goroutine, the same as a scheduled run, Stop waits for it as well
+	Trigger(name string) error
+	// MustTrigger run the job immediately, panic if the job does not exist
+	MustTrigger(name string)
 	// Info get job info
 	Info(name string) (Job, error)
+	// InfoByID get job info by the id of its entry in the underlying cron. The id changes when the job is
+	// rescheduled (Continue, UpdatePlan, Reconcile), and paused jobs have no entry, so they can not be found
+	InfoByID(id cron.EntryID) (Job, error)
+	// List get all jobs added by users with their next execution time, ordered by name
+	List() []Job
+	// ListByTag get all jobs with the tag, ordered by name
+	ListByTag(tag string) []Job
+	// ListInternal get all internal tasks added by scheduler itself, ordered by name. Internal tasks can not be
+	// removed, paused, continued, triggered, replanned or replaced, these operations return ErrInternalJob for them.
+	// Their names start with InternalJobPrefix, which can not be used by the jobs added by users
+	ListInternal() []Job
+	// EntryCount get the number of entries in the underlying cron, including internal tasks
+	EntryCount() int
+	// CheckConsistency check whether the entries in the underlying cron match the registered jobs
+	CheckConsistency() error
+
+	// Reconcile apply the minimal changes to make the registered jobs match the desired jobs
+	Reconcile(desired []JobConfig) (ReconcileResult, error)
+
+	// RestoreFrom apply the plans and paused state saved in store to the registered jobs, and save the
+	// jobs to store on every change after that, it should be called after all jobs are added
+	RestoreFrom(store JobStore) error
+
+	// CheckHealth check whether the scheduler is running and all active jobs are processed in time, see HealthChecker
+	CheckHealth() error
+
+	// Stats get the aggregate statistics of all jobs
+	Stats() SchedulerStats
+	// JobStats get the execution statistics of job
+	JobStats(name string) (JobStats, error)
+	// Timeline get the upcoming executions of all active jobs within the duration, sorted by time, at most limit
+	// executions are returned, limit <= 0 means DefaultTimelineLimit
+	Timeline(within time.Duration, limit int) []ScheduledRun
+	// History get the latest executions of job saved by the recorder, newest first, at most limit records are
+	// returned, limit <= 0 means DefaultHistoryLimit. The recorder must be a HistoryRecorder, see NewMemoryRecorder
+	History(name string, limit int) ([]ScheduleRecord, error)
+	// WriteMetrics write the stats of scheduler in OpenMetrics text format
+	WriteMetrics(w io.Writer) error
+	// TimeUntilNext get the duration until the next execution of job, ErrJobPaused is returned for paused job
+	TimeUntilNext(name string) (time.Duration, error)
 
 	// Start cron manager
 	Start()
-	// Stop cron job manager
+	// PrepareForShutdown stop scheduling new executions, wait for the executions in progress, and release the
+	// distributed locks of all jobs, so that other nodes can take over the jobs without waiting for lock expiration
+	PrepareForShutdown()
+	// Stop cron job manager, it cancels the contexts of running executions, waits for them as SetDrain
+	// configured, and releases the distributed locks of jobs after that
 	Stop()
+	// StopWithTimeout stop cron job manager, and wait for the running executions at most timeout, the executions
+	// not finished in timeout are detached. timeout <= 0 means waiting until all of them are finished
+	StopWithTimeout(timeout time.Duration)
 
+	// LockManagerBuilder set the builder of distributed locks, it applies to the jobs already added as well.
+	// Locks are acquired per execution, and the held locks are refreshed by an internal task (see OnLockLost),
+	// so EntryCount always equals the number of active jobs plus the internal tasks like heartbeat
 	LockManagerBuilder(builder LockManagerBuilder)
+	// OnLockLost add a handler called when the distributed lock held by a job is lost (TryLock returns ErrLockFailed
+	// after the lock is acquired), the runs of the job in progress are cancelled before handlers are called.
+	// Other errors of TryLock can not tell whether the lock is still held, they never make the lock lost
+	OnLockLost(handler func(name string))
+	// SetLockRefreshInterval set the interval to refresh the distributed locks held by jobs, default is 60s.
+	// The interval should be shorter than the TTL of locks (half of it is recommended), otherwise the lock
+	// may expire between two refreshes, and another node takes over the job
+	SetLockRefreshInterval(interval time.Duration)
+	// SetClock set the clock used by scheduler to get current time, mostly used for testing
+	SetClock(clock Clock)
+	// SetRecorder set a recorder which records every execution of jobs
+	SetRecorder(recorder Recorder)
+	// SetSpecRewriter set a rewriter which can modify or reject the plan of jobs before they are added
+	SetSpecRewriter(rewriter SpecRewriter)
+	// SetWaitForLeadership make Start block until the distributed locks of all jobs are acquired, or the timeout elapsed
+	SetWaitForLeadership(timeout time.Duration)
+	// SetCronMode set the mode used to interpret plans, it should be called before any job is added
+	SetCronMode(mode CronMode)
+	// ValidatePlan check whether the plan is valid for the parser of scheduler, without adding a job
+	ValidatePlan(plan string) error
+	// Describe return a human-readable summary of the plan
+	Describe(plan string) (string, error)
+	// SetParserOptions set the fields of cron parser used to interpret plans, it's a more flexible version of
+	// SetCronMode, e.g. cron.SecondOptional makes the seconds field optional. It should be called before any job is added.
+	// Descriptors like "@every 10s" can only be used in plans when cron.Descriptor is included in options. The
+	// internal tasks of scheduler (heartbeat, lock refresh) are not affected, they always use the default parser
+	SetParserOptions(options cron.ParseOption)
+	// SetPanicHandler set a handler which is called with the stack when a job panics, it's called before the panic formatter
+	SetPanicHandler(handler PanicHandler)
+	// SetPanicFormatter set a formatter which converts the panic of jobs into errors, it's called after the panic handler
+	SetPanicFormatter(formatter PanicFormatter)
+	// SetTracer set a tracer which starts a span for every attempt of job execution, e.g. an OpenTelemetry tracer
+	SetTracer(tracer Tracer)
+	// Use register middlewares which wrap every execution of jobs, the first registered one is the outermost
+	Use(middlewares ...Middleware)
+	// EnableEventPublishing publish JobStartedEvent, JobCompletedEvent and JobFailedEvent for every execution of jobs
+	EnableEventPublishing(enabled bool)
+	// OnBeforeRun register a callback invoked before every execution of any job
+	OnBeforeRun(fn func(name string))
+	// OnAfterRun register a callback invoked after every execution of any job, err is not nil if the execution failed
+	OnAfterRun(fn func(name string, err error, d time.Duration))
+	// SetExecutorPoolSize limit the number of concurrent executions of all jobs, it can be changed at runtime
+	SetExecutorPoolSize(n int)
+	// SetMaxConcurrency limit the number of concurrent executions of all jobs, it shares the executor pool with
+	// SetExecutorPoolSize, what to do when the limit is reached is set by SetMaxConcurrencyWait
+	SetMaxConcurrency(n int)
+	// SetMaxConcurrencyWait set the max time executions wait for a free executor when the concurrency limit is reached,
+	// they are skipped after that. maxWait = 0 means skipping immediately, maxWait < 0 means waiting without limit (default)
+	SetMaxConcurrencyWait(maxWait time.Duration)
+	// SetTickSpread spread the executions of jobs scheduled at the same time over the window
+	SetTickSpread(window time.Duration)
+	// SetRunScope create a RunScope for every run, at most limit runs can hold a scope at the same time
+	SetRunScope(limit int)
+	// SetOnReadyLockTimeout set the max time to wait for the distributed lock before the on-ready run of jobs,
+	// the on-ready run is abandoned on timeout
+	SetOnReadyLockTimeout(timeout time.Duration)
+	// SetDrain make Stop wait for the running executions at most timeout, and then act as policy. By default
+	// (timeout <= 0), Stop waits until all running executions are finished
+	SetDrain(timeout time.Duration, policy DrainPolicy)
+	// SetDefaults set the default options applied to all jobs added after it, the options of job override them
+	SetDefaults(options ...JobOption)
+	// SetLabels set the labels of scheduler, they are added to all metrics and webhooks. Label names must be
+	// valid OpenMetrics label names, and job, le are reserved by scheduler
+	SetLabels(labels map[string]string) error
+	// SetTagConcurrency limit the number of concurrent executions of jobs with the tag
+	SetTagConcurrency(tag string, limit int)
+	// SetHeartbeat enable the heartbeat of scheduler, it updates LastHeartbeat and publishes a HeartbeatEvent if publishEvent is true
+	SetHeartbeat(interval time.Duration, publishEvent bool)
+	// LastHeartbeat return the time of last heartbeat, it can be used to check whether the scheduler is alive
+	LastHeartbeat() time.Time
 }
 
 type LockManager interface {
@@ -54,32 +223,154 @@ type LockManager interface {
 
 var ErrLockFailed = errors.New("lock failed")
 
+// ErrJobNotFound is returned when the job with the given name is not registered
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobPaused is returned when the operation can not be applied to a paused job
+var ErrJobPaused = errors.New("job paused")
+
+// ErrInternalJob is returned when the operation for jobs added by users is applied to an internal task, see ListInternal
+var ErrInternalJob = errors.New("internal job can not be changed")
+
+// ErrHistoryNotSupported is returned by History when the recorder of scheduler is not a HistoryRecorder
+var ErrHistoryNotSupported = errors.New("recorder does not support history")
+
 type LockManagerBuilder func(name string) LockManager
 
+// SpecRewriter rewrite the plan for job before it's added to scheduler, return an error to reject the job
+type SpecRewriter func(name, plan string) (string, error)
+
 type schedulerImpl struct {
 	lock     sync.RWMutex
 	resolver infra.Resolver
 	cr       *cron.Cron
 
 	lockManagerBuilder LockManagerBuilder
+	clock              Clock
+	recorder           Recorder
+	specRewriter       SpecRewriter
+	parser             cron.Parser
+
+	waitForLeadershipTimeout time.Duration
+	onReadyLockTimeout       time.Duration
+
+	// publisherWarning make sure the warning for missing event publisher is logged only once
+	publisherWarning sync.Once
+	// resolverWarning make sure the warning for resolver without child scope is logged only once
+	resolverWarning sync.Once
+
+	// baseCtx is the parent context of all runs, it's cancelled when scheduler stopped
+	baseCtx       context.Context
+	cancelBaseCtx context.CancelFunc
+
+	heartbeatInterval     time.Duration
+	heartbeatPublishEvent bool
+	lastHeartbeat         time.Time
 
 	jobs map[string]*Job
+	// snapshot is an immutable view of jobs for lock-free reads, see unlock
+	snapshot      atomic.Pointer[jobsSnapshot]
+	mutexGroups   map[string]*sync.Mutex
+	tagSemaphores map[string]chan struct{}
+
+	labels        map[string]string
+	defaults      []JobOption
+	nameGenerator func() string
+	autoSeq       uint64
+
+	drainTimeout time.Duration
+	drainPolicy  DrainPolicy
+
+	startedAt     time.Time
+	running       bool
+	locksReleased bool
+	tickSpread    time.Duration
+	// spreadPeers is the jobs scheduled at the last tick, see spread
+	spreadPeers *tickPeers
+	spreadLock  sync.Mutex
+	executors   *executorPool
+	locks       *lockTracker
+	triggered   runTracker
+	seq         uint64
+
+	lockRefreshInterval time.Duration
+
+	panicHandler       PanicHandler
+	panicFormatter     PanicFormatter
+	beforeRunCallbacks []func(name string)
+	eventPublishing    bool
+	middlewares        []Middleware
+
+	store             JobStore
+	persistLock       sync.Mutex
+	afterRunCallbacks []func(name string, err error, d time.Duration)
+
+	runScopeEnabled   bool
+	runScopeSemaphore chan struct{}
+	tracer            Tracer
+
+	// serial executes the changes made by scheduler itself on the command goroutine of serialScheduler,
+	// it's nil for the scheduler created by NewManager, see serially
+	serial func(fn func())
 }
 
 // Job is a job object
 type Job struct {
-	ID          cron.EntryID
-	Name        string
-	Plan        string
-	handler     func()
-	Paused      bool
+	ID        cron.EntryID `json:"id"`
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace,omitempty"`
+	ShortName string       `json:"short_name"`
+	Plan      string       `json:"plan"`
+	Tags      []string     `json:"tags,omitempty"`
+	// Internal is true for the tasks added by scheduler itself, such as heartbeat, see ListInternal
+	Internal bool `json:"internal,omitempty"`
+	handler  func()
+	// run is the wrapped handler of user job, it's nil for internal jobs
+	run    func(exec *execution)
+	Paused bool `json:"paused"`
+	// PausedUntil is the time when the paused job will be continued automatically, see PauseUntil
+	PausedUntil time.Time `json:"paused_until,omitempty"`
+	// Stats, Running, LastSkipReason and LastSkippedAt are filled from the runtime state of job, see jobState
+	Stats JobStats `json:"stats"`
+	// Running is true when the job has executions in progress
+	Running bool `json:"running"`
+	// LastSkipReason is the reason why the last scheduled execution is skipped
+	LastSkipReason string    `json:"last_skip_reason,omitempty"`
+	LastSkippedAt  time.Time `json:"last_skipped_at,omitempty"`
+	// NextRun is the next execution time of job, it's only filled by List, and zero for paused jobs
+	NextRun time.Time `json:"next_run,omitempty"`
+	// RunAt is the execution time of one-shot job added by RunAt, it's zero for other jobs
+	RunAt       time.Time `json:"run_at,omitempty"`
 	lockManager LockManager
+	// runLockManager is the lock held during each execution, see WithRunLock
+	runLockManager LockManager
+	options        jobOptions
+	// resumeTimer continues the job at PausedUntil
+	resumeTimer *time.Timer
+	// pausedByAll is true when the job is paused by PauseAll
+	pausedByAll bool
+	mutex       *sync.Mutex
+	// runningMutex is held during each execution, see WithSkipIfRunning
+	runningMutex *sync.Mutex
+	// seq is the registration order of job
+	seq uint64
+	// parser is the parser of scheduler when the job is added
+	parser cron.ScheduleParser
+	// sc is the parsed schedule of Plan, it's nil for dynamic jobs, see schedule
+	sc cron.Schedule
+
+	// state is the runtime state shared by all copies of job, see jobState
+	state *atomic.Pointer[jobState]
+	// cycle tracks the start of current cycle for checking prerequisites, see WithRunAfter
+	cycle   *cycleTracker
+	addedAt time.Time
+	// intervalReserved is true when an execution passed the min interval check and it's not finished, see tooSoon
+	intervalReserved bool
 }
 
 // Next get execute plan for job
 func (job Job) Next(nextNum int) ([]time.Time, error) {
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	sc, err := parser.Parse(job.Plan)
+	sc, err := job.schedule()
 	if err != nil {
 		return nil, err
 	}
@@ -96,144 +387,503 @@ func (job Job) Next(nextNum int) ([]time.Time, error) {
 
 // NewManager create a new Scheduler
 func NewManager(resolver infra.Resolver) Scheduler {
-	m := schedulerImpl{resolver: resolver, jobs: make(map[string]*Job)}
+	m := schedulerImpl{resolver: resolver, jobs: make(map[string]*Job), mutexGroups: make(map[string]*sync.Mutex), tagSemaphores: make(map[string]chan struct{}), clock: realClock{}, parser: planParser, executors: newExecutorPool(), locks: newLockTracker()}
+	m.snapshot.Store(&jobsSnapshot{jobs: map[string]Job{}, ids: map[cron.EntryID]string{}, list: []Job{}})
+	m.resetBaseContext()
 	resolver.MustResolve(func(cr *cron.Cron) { m.cr = cr })
 
 	return &m
 }
 
 func (c *schedulerImpl) LockManagerBuilder(builder LockManagerBuilder) {
+	c.lock.Lock()
+	defer c.unlock()
+
 	c.lockManagerBuilder = builder
+	if builder == nil {
+		return
+	}
+
+	// jobs added before the builder is set should be protected by distributed locks too
+	for name, job := range c.jobs {
+		if job.lockManager == nil && !job.Internal && !job.options.ignoreLock {
+			job.lockManager = builder(name)
+		}
+	}
+
+	// the refresh task is added by Start, so it's added here when the scheduler is running
+	if c.running {
+		c.addLockRefresh()
+	}
 }
 
-func (c *schedulerImpl) MustAddAndRunOnServerReady(name string, plan string, handler interface{}) {
-	if err := c.AddAndRunOnServerReady(name, plan, handler); err != nil {
+func (c *schedulerImpl) SetClock(clock Clock) {
+	c.clock = clock
+}
+
+func (c *schedulerImpl) SetRecorder(recorder Recorder) {
+	c.recorder = recorder
+}
+
+func (c *schedulerImpl) SetSpecRewriter(rewriter SpecRewriter) {
+	c.specRewriter = rewriter
+}
+
+func (c *schedulerImpl) SetWaitForLeadership(timeout time.Duration) {
+	c.waitForLeadershipTimeout = timeout
+}
+
+func (c *schedulerImpl) MustAddAndRunOnServerReady(name string, plan string, handler interface{}, options ...JobOption) {
+	if err := c.AddAndRunOnServerReady(name, plan, handler, options...); err != nil {
 		panic(err)
 	}
 }
 
-func (c *schedulerImpl) AddAndRunOnServerReady(name string, plan string, handler interface{}) error {
-	handler, err := c.add(name, plan, handler)
+func (c *schedulerImpl) AddAndRunOnServerReady(name string, plan string, handler interface{}, options ...JobOption) error {
+	runner, err := c.add(name, plan, handler, options...)
 	if err != nil {
 		return err
 	}
 
 	return c.resolver.Resolve(func(hook infra.Hook) {
-		hook.OnServerReady(handler)
+		hook.OnServerReady(c.onReadyHandler(name, runner))
 	})
 }
 
-func (c *schedulerImpl) MustAdd(name string, plan string, handler interface{}) {
-	if err := c.Add(name, plan, handler); err != nil {
+func (c *schedulerImpl) MustAdd(name string, plan string, handler interface{}, options ...JobOption) {
+	if err := c.Add(name, plan, handler, options...); err != nil {
 		panic(err)
 	}
 }
 
-func (c *schedulerImpl) Add(name string, plan string, handler interface{}) error {
-	_, err := c.add(name, plan, handler)
+func (c *schedulerImpl) Add(name string, plan string, handler interface{}, options ...JobOption) error {
+	_, err := c.add(name, plan, handler, options...)
 	return err
 }
 
-func (c *schedulerImpl) add(name string, plan string, handler interface{}) (func(), error) {
+func (c *schedulerImpl) AddAndReturnID(name string, plan string, handler interface{}, options ...JobOption) (cron.EntryID, error) {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	defer c.unlock()
+
+	if _, err := c.addJob(name, plan, handler, options...); err != nil {
+		return 0, err
+	}
+
+	return c.jobs[name].ID, nil
+}
+
+func (c *schedulerImpl) add(name string, plan string, handler interface{}, options ...JobOption) (func(), error) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	return c.addJob(name, plan, handler, options...)
+}
+
+// rewritePlan apply the spec rewriter to plan
+func (c *schedulerImpl) rewritePlan(name string, plan string) (string, error) {
+	if c.specRewriter == nil {
+		return plan, nil
+	}
+
+	rewritten, err := c.specRewriter(name, plan)
+	if err != nil {
+		return "", errors.Wrapf(err, "[glacier] plan for job [%s] rejected", name)
+	}
+
+	return rewritten, nil
+}
+
+// addJob add a job to scheduler, the caller must hold the write lock
+func (c *schedulerImpl) addJob(name string, plan string, handler interface{}, options ...JobOption) (func(), error) {
+	job, err := c.newJob(name, plan, handler, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRunAfter(job, func(name string) bool {
+		reg, ok := c.jobs[name]
+		return ok && !reg.Internal
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := c.registerJob(job); err != nil {
+		return nil, err
+	}
+
+	return job.handler, nil
+}
+
+// newJob validate the job and build it without changing the scheduler, the job is added by registerJob.
+// The caller must hold the lock
+func (c *schedulerImpl) newJob(name string, plan string, handler interface{}, options ...JobOption) (*Job, error) {
+	if err := reservedNameError(name); err != nil {
+		return nil, err
+	}
+
+	if err := validateHandler(handler); err != nil {
+		return nil, errors.Wrapf(err, "[glacier] invalid handler for job [%s]", name)
+	}
 
 	if reg, existed := c.jobs[name]; existed {
 		return nil, fmt.Errorf("job with name [%s] already existed: %d | %s", name, reg.ID, reg.Plan)
 	}
 
-	var lockManager LockManager
-	if c.lockManagerBuilder != nil {
-		lockManager = c.lockManagerBuilder(name)
+	opts := c.jobOptions(options)
+	if opts.err != nil {
+		return nil, errors.Wrapf(opts.err, "[glacier] invalid options for job [%s]", name)
 	}
 
-	jobHandler := c.wrapJobHandler(name, handler, lockManager)
-	id, err := c.cr.AddFunc(plan, jobHandler)
+	// the plan of dynamic and one-shot job is a placeholder, it's not rewritten
+	if opts.dynamic == nil {
+		rewritten, err := c.rewritePlan(name, plan)
+		if err != nil {
+			return nil, err
+		}
 
-	if err != nil {
-		return nil, errors.Wrap(err, "[glacier] add cron job failed")
+		plan = rewritten
+	}
+
+	job := &Job{
+		Name:    name,
+		Plan:    plan,
+		Paused:  false,
+		options: opts,
+		state:   newJobState(),
+	}
+
+	if opts.once != nil {
+		job.RunAt = opts.once.at
+	}
+
+	job.Tags = job.options.tags
+	job.Namespace, job.ShortName = job.options.namespace, name
+	if job.Namespace != "" {
+		job.ShortName = strings.TrimPrefix(name, job.Namespace+".")
+	}
+
+	if c.lockManagerBuilder != nil && !job.options.ignoreLock {
+		job.lockManager = c.lockManagerBuilder(name)
+	}
+
+	if job.options.runLock {
+		if c.lockManagerBuilder == nil {
+			return nil, fmt.Errorf("[glacier] job [%s] requires a run lock, but no lock manager is configured", name)
+		}
+
+		job.runLockManager = c.lockManagerBuilder(runLockName(name))
+	}
+
+	job.parser = c.parser
+	if opts.dynamic == nil {
+		sc, err := job.parseSchedule()
+		if err != nil {
+			return nil, errors.Wrap(err, "[glacier] add cron job failed")
+		}
+
+		job.sc = sc
+	}
+
+	if job.options.skipIfRunning {
+		job.runningMutex = &sync.Mutex{}
+	}
+
+	job.cycle = &cycleTracker{}
+	job.run = c.wrapJobHandler(job, handler)
+	job.handler = func() { job.run(&execution{}) }
+
+	return job, nil
+}
+
+// registerJob add the job built by newJob to scheduler and cron, the caller must hold the write lock
+func (c *schedulerImpl) registerJob(job *Job) error {
+	if job.options.mutexGroup != "" {
+		if _, ok := c.mutexGroups[job.options.mutexGroup]; !ok {
+			c.mutexGroups[job.options.mutexGroup] = &sync.Mutex{}
+		}
+
+		job.mutex = c.mutexGroups[job.options.mutexGroup]
 	}
 
-	c.jobs[name] = &Job{
-		ID:          id,
-		Name:        name,
-		Plan:        plan,
-		handler:     jobHandler,
-		Paused:      false,
-		lockManager: lockManager,
+	id, err := c.schedule(job, job.Plan)
+	if err != nil {
+		return errors.Wrap(err, "[glacier] add cron job failed")
 	}
 
+	c.seq++
+	job.seq = c.seq
+	job.addedAt = c.clock.Now()
+	job.ID = id
+	c.jobs[job.Name] = job
+
 	if infra.DEBUG {
-		log.Debugf("[glacier] add job [%s] to scheduler(%s)", name, plan)
+		log.WithFields(infra.Fields{"job": job.Name, "plan": job.Plan}).Debugf("[glacier] add job [%s] to scheduler(%s)", job.Name, job.Plan)
 	}
 
-	return jobHandler, nil
+	return nil
 }
 
-func (c *schedulerImpl) wrapJobHandler(name string, handler interface{}, lockManager LockManager) func() {
+func (c *schedulerImpl) wrapJobHandler(job *Job, handler interface{}) func(exec *execution) {
 	hh, ok := handler.(JobHandler)
 	if !ok {
 		hh = newHandler(handler)
 	}
 
-	return func() {
+	name := job.Name
+	return func(exec *execution) {
+		defer exec.releaseGuards()
+
+		skip := func(reason string) {
+			exec.skipReason = reason
+			c.skip(job, reason)
+		}
+
+		if job.options.activeHours != nil && !job.options.activeHours.contains(c.clock.Now()) {
+			if infra.DEBUG {
+				log.WithFields(infra.Fields{"job": name, "reason": SkipReasonInactive}).Debugf("[glacier] cron job [%s] skipped because it's out of active hours", name)
+			}
+
+			skip(SkipReasonInactive)
+			return
+		}
+
+		if c.inInitialDelay(job) {
+			if infra.DEBUG {
+				log.WithFields(infra.Fields{"job": name, "reason": SkipReasonInitialDelay}).Debugf("[glacier] cron job [%s] skipped because it's in initial delay", name)
+			}
+
+			skip(SkipReasonInitialDelay)
+			return
+		}
+
+		tooSoon, release := c.tooSoon(job)
+		if tooSoon {
+			if infra.DEBUG {
+				log.WithFields(infra.Fields{"job": name, "reason": SkipReasonMinInterval}).Debugf("[glacier] cron job [%s] skipped because the min interval since last run is not reached", name)
+			}
+
+			skip(SkipReasonMinInterval)
+			return
+		}
+		exec.hold(release)
+
+		if c.inBackoff(job) {
+			if infra.DEBUG {
+				log.WithFields(infra.Fields{"job": name, "reason": SkipReasonBackoff}).Debugf("[glacier] cron job [%s] skipped because it's in failure backoff", name)
+			}
+
+			skip(SkipReasonBackoff)
+			return
+		}
+
+		if reason := c.unmetDependency(job); reason != "" {
+			if infra.WARN {
+				log.WithFields(infra.Fields{"job": name, "reason": reason}).Warningf("[glacier] cron job [%s] skipped because %s", name, reason)
+			}
+
+			skip(reason)
+			return
+		}
+
+		if job.runningMutex != nil {
+			if !job.runningMutex.TryLock() {
+				if infra.DEBUG {
+					log.WithFields(infra.Fields{"job": name, "reason": SkipReasonRunning}).Debugf("[glacier] cron job [%s] skipped because the previous execution is still running", name)
+				}
+
+				skip(SkipReasonRunning)
+				return
+			}
+
+			exec.hold(job.runningMutex.Unlock)
+		}
+
+		c.lock.RLock()
+		lockManager := job.lockManager
+		c.lock.RUnlock()
+
 		if lockManager != nil {
 			if err := lockManager.TryLock(context.TODO()); err != nil {
 				if errors.Is(err, ErrLockFailed) {
+					c.lockFailed(name, err)
 					if infra.DEBUG {
-						log.Debugf("[glacier] cron job [%s] can not start because it doesn't get the lock", name)
+						log.WithFields(infra.Fields{"job": name, "reason": SkipReasonNotLeader}).Debugf("[glacier] cron job [%s] can not start because it doesn't get the lock", name)
 					}
 
+					skip(SkipReasonNotLeader)
 					return
 				}
 
-				log.Errorf("[glacier] cron job [%s] can not start because it can not get the lock: %v", name, err)
+				log.WithFields(infra.Fields{"job": name, "error": err}).Errorf("[glacier] cron job [%s] can not start because it can not get the lock", name)
+				skip(SkipReasonLockError)
 				return
 			}
+
+			c.lockAcquired(name)
 		}
 
-		if infra.DEBUG {
-			log.Debugf("[glacier] cron job [%s] running", name)
+		if job.mutex != nil {
+			if !job.mutex.TryLock() {
+				if infra.WARN {
+					log.WithFields(infra.Fields{"job": name, "reason": SkipReasonMutexGroup, "mutex_group": job.options.mutexGroup}).Warningf("[glacier] cron job [%s] skipped because another job in mutex group [%s] is running", name, job.options.mutexGroup)
+				}
+
+				skip(SkipReasonMutexGroup)
+				return
+			}
+
+			exec.hold(job.mutex.Unlock)
 		}
 
-		startTs := time.Now()
+		if release, ok := c.acquireTagSemaphores(job); ok {
+			exec.hold(release)
+		} else {
+			if infra.WARN {
+				log.WithFields(infra.Fields{"job": name, "reason": SkipReasonTagConcurrency}).Warningf("[glacier] cron job [%s] skipped because the concurrency limit of its tags is reached", name)
+			}
+
+			skip(SkipReasonTagConcurrency)
+			return
+		}
+
+		if job.runLockManager != nil {
+			if err := job.runLockManager.TryLock(context.TODO()); err != nil {
+				if errors.Is(err, ErrLockFailed) {
+					if infra.WARN {
+						log.WithFields(infra.Fields{"job": name, "reason": SkipReasonRunLock}).Warningf("[glacier] cron job [%s] skipped because its previous execution still holds the run lock", name)
+					}
+
+					skip(SkipReasonRunLock)
+					return
+				}
+
+				log.WithFields(infra.Fields{"job": name, "error": err}).Errorf("[glacier] cron job [%s] can not start because it can not get the run lock", name)
+				skip(SkipReasonLockError)
+				return
+			}
+
+			// the run lock is released once the execution is finished or timed out, so a hanging handler
+			// never blocks the job on other nodes
+			defer func() {
+				if err := job.runLockManager.Release(context.TODO()); err != nil {
+					log.WithFields(infra.Fields{"job": name, "error": err}).Errorf("[glacier] cron job [%s] can not release run lock", name)
+				}
+			}()
+		}
+
+		c.lock.RLock()
+		baseCtx := c.baseCtx
+		c.lock.RUnlock()
+
+		if !c.executors.acquire(baseCtx) {
+			// the execution waiting for a free executor is abandoned when the scheduler is stopped
+			if baseCtx.Err() != nil {
+				if infra.DEBUG {
+					log.WithFields(infra.Fields{"job": name}).Debugf("[glacier] cron job [%s] is abandoned because scheduler is stopped", name)
+				}
+
+				return
+			}
+
+			if infra.WARN {
+				log.WithFields(infra.Fields{"job": name, "reason": SkipReasonConcurrency}).Warningf("[glacier] cron job [%s] skipped because max concurrency of scheduler is reached", name)
+			}
+
+			skip(SkipReasonConcurrency)
+			return
+		}
+		exec.hold(c.executors.release)
+
+		// the run starts when the middlewares call next, so an execution short-circuited by them is skipped
+		var startTs time.Time
+		var runID string
+		start := func() {
+			startTs, runID = c.clock.Now(), newRunID()
+			c.beginRun(job)
+			exec.ran = true
+			c.beforeRun(name)
+			c.publishRunStarted(name, runID, startTs)
+
+			if infra.DEBUG {
+				log.WithFields(infra.Fields{"job": name, "run_id": runID}).Debugf("[glacier] cron job [%s] running", name)
+			}
+		}
+
+		var runResult interface{}
+		var runErr error
 		defer func() {
-			if err := recover(); err != nil {
-				log.Errorf("[glacier] cron job [%s] stopped with some errors: %v, took %s", name, err, time.Since(startTs))
+			err := recover()
+			if !exec.ran {
+				if err == nil {
+					if infra.DEBUG {
+						log.WithFields(infra.Fields{"job": name, "reason": SkipReasonMiddleware}).Debugf("[glacier] cron job [%s] skipped by middleware", name)
+					}
+
+					skip(SkipReasonMiddleware)
+					return
+				}
+
+				// a middleware panicked before calling next, it fails the execution like a panicking job
+				start()
+			}
+
+			if err != nil {
+				runErr = c.recoverPanic(job, err, debug.Stack())
+				log.WithFields(infra.Fields{"job": name, "run_id": runID, "error": runErr, "duration": c.clock.Now().Sub(startTs)}).Errorf("[glacier] cron job [%s] stopped with some errors", name)
 			} else {
 				if infra.DEBUG {
-					log.Debugf("[glacier] cron job [%s] stopped, took %s", name, time.Since(startTs))
+					log.WithFields(infra.Fields{"job": name, "run_id": runID, "duration": c.clock.Now().Sub(startTs)}).Debugf("[glacier] cron job [%s] stopped", name)
 				}
 			}
+
+			c.endRun(job, startTs, runResult, runErr)
+			c.record(name, exec.scheduled, startTs, runErr)
+			c.afterRun(name, runErr, c.clock.Now().Sub(startTs))
+			c.publishRunFinished(name, runID, runErr, c.clock.Now().Sub(startTs))
+			c.notifyWebhook(job, startTs, c.clock.Now().Sub(startTs), runErr)
 		}()
-		if err := c.resolver.Resolve(hh.Handle); err != nil {
-			log.Errorf("[glacier] cron job [%s] failed, Err: %v, Stack: \n%s", name, err, debug.Stack())
-		}
+		c.applyMiddlewares(job, func() {
+			start()
+
+			result, err := c.runWithRetry(job, hh, runID, exec)
+			runResult = result
+			if err != nil {
+				runErr = err
+				log.WithFields(infra.Fields{"job": name, "run_id": runID, "error": err, "stack": string(debug.Stack())}).Errorf("[glacier] cron job [%s] failed", name)
+			}
+		})()
 	}
 }
 
 func (c *schedulerImpl) Remove(name string) error {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	defer c.unlock()
 
-	reg, exist := c.jobs[name]
-	if !exist {
-		return errors.Errorf("[glacier] job with name [%s] not found", name)
+	return c.removeJob(name)
+}
+
+// removeJob remove a job from scheduler, the caller must hold the write lock
+func (c *schedulerImpl) removeJob(name string) error {
+	reg, err := c.userJob(name)
+	if err != nil {
+		return err
 	}
 
 	if reg.lockManager != nil {
+		c.locks.forget(name)
 		if err := reg.lockManager.Release(context.TODO()); err != nil {
-			log.Errorf("[glacier] cron job [%s] can not release lock: %v", name, err)
+			log.WithFields(infra.Fields{"job": name, "error": err}).Errorf("[glacier] cron job [%s] can not release lock", name)
 		}
 	}
 
 	delete(c.jobs, name)
+	c.cancelResume(reg)
 	if !reg.Paused {
 		c.cr.Remove(reg.ID)
 	}
 
 	if infra.DEBUG {
-		log.Debugf("[glacier] remove job [%s] from scheduler", name)
+		log.WithFields(infra.Fields{"job": name}).Debugf("[glacier] remove job [%s] from scheduler", name)
 	}
 
 	return nil
@@ -241,13 +891,22 @@ func (c *schedulerImpl) Remove(name string) error {
 
 func (c *schedulerImpl) Pause(name string) error {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	defer c.unlock()
 
-	reg, exist := c.jobs[name]
-	if !exist {
-		return errors.Errorf("[glacier] job with name [%s] not found", name)
+	return c.pauseJob(name)
+}
+
+// pauseJob pause a job, the caller must hold the write lock
+func (c *schedulerImpl) pauseJob(name string) error {
+	reg, err := c.userJob(name)
+	if err != nil {
+		return err
 	}
 
+	// the job paused by PauseAll is paused individually now, ContinueAll won't continue it,
+	// and the job paused by PauseUntil is paused indefinitely now
+	reg.pausedByAll = false
+	c.cancelResume(reg)
 	if reg.Paused {
 		return nil
 	}
@@ -256,7 +915,7 @@ func (c *schedulerImpl) Pause(name string) error {
 	reg.Paused = true
 
 	if infra.DEBUG {
-		log.Debugf("[glacier] change job [%s] to paused", name)
+		log.WithFields(infra.Fields{"job": name}).Debugf("[glacier] change job [%s] to paused", name)
 	}
 
 	return nil
@@ -264,57 +923,286 @@ func (c *schedulerImpl) Pause(name string) error {
 
 func (c *schedulerImpl) Continue(name string) error {
 	c.lock.Lock()
-	defer c.lock.Unlock()
+	defer c.unlock()
+
+	return c.continueJob(name)
+}
+
+func (c *schedulerImpl) UpdatePlan(name string, plan string) error {
+	c.lock.Lock()
+	defer c.unlock()
+
+	job, err := c.userJob(name)
+	if err != nil {
+		return err
+	}
 
-	reg, exist := c.jobs[name]
-	if !exist {
-		return errors.Errorf("[glacier] job with name [%s] not found", name)
+	if job.options.dynamic != nil {
+		return fmt.Errorf("[glacier] update plan failed: job [%s] is a dynamic job", name)
+	}
+
+	plan, err = c.rewritePlan(name, plan)
+	if err != nil {
+		return err
+	}
+
+	// validate the plan before the old entry is removed, the plan of paused job is only stored
+	if _, err := c.parser.Parse(plan); err != nil {
+		return errors.Wrapf(err, "[glacier] update plan failed: invalid plan for job [%s]", name)
+	}
+
+	if job.Plan == plan {
+		return nil
+	}
+
+	return c.reschedule(job, plan)
+}
+
+// continueJob continue a paused job, the caller must hold the write lock
+func (c *schedulerImpl) continueJob(name string) error {
+	reg, err := c.userJob(name)
+	if err != nil {
+		return err
 	}
 
 	if !reg.Paused {
 		return nil
 	}
 
-	id, err := c.cr.AddFunc(reg.Plan, reg.handler)
+	id, err := c.schedule(reg, reg.Plan)
 	if err != nil {
 		return errors.Wrap(err, "[glacier] change job from paused to continue failed")
 	}
 
 	reg.Paused = false
+	reg.pausedByAll = false
 	reg.ID = id
+	c.cancelResume(reg)
 
 	if infra.DEBUG {
-		log.Debugf("[glacier] change job [%s] to continue", name)
+		log.WithFields(infra.Fields{"job": name}).Debugf("[glacier] change job [%s] to continue", name)
 	}
 
 	return nil
 }
 
 func (c *schedulerImpl) Info(name string) (Job, error) {
+	if job, ok := c.snapshot.Load().jobs[name]; ok {
+		return job.withState(), nil
+	}
+
+	return Job{}, jobNotFoundError(name)
+}
+
+func (c *schedulerImpl) InfoByID(id cron.EntryID) (Job, error) {
+	snap := c.snapshot.Load()
+	if name, ok := snap.ids[id]; ok {
+		return snap.jobs[name].withState(), nil
+	}
+
+	return Job{}, errors.Wrapf(ErrJobNotFound, "[glacier] job with id [%d]", id)
+}
+
+func (c *schedulerImpl) List() []Job {
+	list := c.snapshot.Load().list
+
+	jobs := make([]Job, len(list))
+	for i, job := range list {
+		jobs[i] = job.withState()
+	}
+
+	nexts := make(map[cron.EntryID]time.Time)
+	for _, entry := range c.cr.Entries() {
+		nexts[entry.ID] = entry.Next
+	}
+
+	for i := range jobs {
+		if jobs[i].Paused {
+			continue
+		}
+
+		jobs[i].NextRun = nexts[jobs[i].ID]
+		// the entry has no next time before cron started, calculate it from plan
+		if jobs[i].NextRun.IsZero() {
+			if next, err := jobs[i].Next(1); err == nil {
+				jobs[i].NextRun = next[0]
+			}
+		}
+	}
+
+	return jobs
+}
+
+func (c *schedulerImpl) TimeUntilNext(name string) (time.Duration, error) {
 	c.lock.RLock()
-	defer c.lock.RUnlock()
+	job, ok := c.jobs[name]
+	if !ok {
+		c.lock.RUnlock()
+		return 0, jobNotFoundError(name)
+	}
 
-	if job, ok := c.jobs[name]; ok {
-		return *job, nil
+	if job.Paused {
+		c.lock.RUnlock()
+		return 0, errors.Wrapf(ErrJobPaused, "[glacier] job with name [%s]", name)
 	}
 
-	return Job{}, fmt.Errorf("[glacier] job with name [%s] not found", name)
+	next := c.cr.Entry(job.ID).Next
+	plan := *job
+	c.lock.RUnlock()
+
+	// the entry has no next time before cron started, calculate it from plan
+	if next.IsZero() {
+		nexts, err := plan.Next(1)
+		if err != nil {
+			return 0, err
+		}
+
+		next = nexts[0]
+	}
+
+	if remain := next.Sub(c.clock.Now()); remain > 0 {
+		return remain, nil
+	}
+
+	return 0, nil
+}
+
+// runLockName return the name of the lock held during each execution of job
+func runLockName(name string) string {
+	return name + "#run"
+}
+
+func jobNotFoundError(name string) error {
+	return errors.Wrapf(ErrJobNotFound, "[glacier] job with name [%s]", name)
 }
 
 func (c *schedulerImpl) Start() {
+	c.start()
+	c.reportStartup()
+}
+
+// start the scheduler without reporting the startup
+func (c *schedulerImpl) start() {
+	if c.waitForLeadershipTimeout > 0 {
+		c.waitForLeadership(c.waitForLeadershipTimeout)
+	}
+
+	c.lock.Lock()
+	c.startedAt = c.clock.Now()
+	c.locksReleased = false
+	c.running = true
+	c.lock.Unlock()
+
+	c.resetBaseContext()
+	c.startHeartbeat()
+	c.startLockRefresh()
 	c.cr.Start()
 }
 
-func (c *schedulerImpl) Stop() {
-	if c.lockManagerBuilder != nil {
-		for _, job := range c.jobs {
-			if job.lockManager != nil {
-				if err := job.lockManager.Release(context.TODO()); err != nil {
-					log.Errorf("[glacier] cron job [%s] can not release lock: %v", job.Name, err)
-				}
+// waitForLeadership try to acquire the distributed locks of all jobs until all of them are acquired or timeout.
+// After timeout, the node acts as a follower for the jobs whose lock is not acquired: they will still try to
+// get the lock at each scheduled time, and only run when the lock is acquired.
+func (c *schedulerImpl) waitForLeadership(timeout time.Duration) {
+	c.lock.RLock()
+	pending := make([]*Job, 0)
+	for _, job := range c.jobs {
+		if job.lockManager != nil {
+			pending = append(pending, job)
+		}
+	}
+	c.lock.RUnlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	startTs := time.Now()
+	for {
+		remains := make([]*Job, 0, len(pending))
+		for _, job := range pending {
+			if err := job.lockManager.TryLock(context.TODO()); err != nil {
+				remains = append(remains, job)
+				continue
+			}
+
+			c.lockAcquired(job.Name)
+		}
+
+		pending = remains
+		if len(pending) == 0 {
+			if infra.DEBUG {
+				log.Debugf("[glacier] all distributed locks for cron jobs acquired, took %s", time.Since(startTs))
 			}
+			return
+		}
+
+		if time.Since(startTs) >= timeout {
+			if infra.WARN {
+				log.WithFields(infra.Fields{"pending": len(pending), "timeout": timeout}).Warningf("[glacier] wait for leadership timeout, %d cron jobs will run as follower until their locks are acquired", len(pending))
+			}
+			return
+		}
+
+		if infra.DEBUG {
+			log.Debugf("[glacier] waiting for leadership, %d cron jobs have not acquired their locks yet", len(pending))
 		}
+
+		time.Sleep(minDuration(time.Second, timeout-time.Since(startTs)))
+	}
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func (c *schedulerImpl) Stop() {
+	c.markStopped()
+	c.cancelRuns()
+	c.drain(c.stopCron())
+	// locks are released after running jobs drained, so other nodes won't take over the jobs still running
+	c.releaseLocks()
+	c.reportShutdown()
+}
+
+func (c *schedulerImpl) StopWithTimeout(timeout time.Duration) {
+	c.markStopped()
+	c.cancelRuns()
+	c.drainWithin(c.stopCron(), timeout, DetachOnTimeout)
+	c.releaseLocks()
+	c.reportShutdown()
+}
+
+// releaseLocks release the distributed locks of all jobs, and return the names of these jobs.
+// The locks are released only once until the scheduler is started again
+func (c *schedulerImpl) releaseLocks() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.locksReleased {
+		return nil
+	}
+
+	c.locksReleased = true
+
+	names := make([]string, 0)
+	for _, job := range c.jobs {
+		if job.lockManager == nil {
+			continue
+		}
+
+		c.locks.forget(job.Name)
+		if err := job.lockManager.Release(context.TODO()); err != nil {
+			log.WithFields(infra.Fields{"job": job.Name, "error": err}).Errorf("[glacier] cron job [%s] can not release lock: %v", job.Name, err)
+			continue
+		}
+
+		names = append(names, job.Name)
 	}
 
-	c.cr.Stop()
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,2529 @@
+package scheduler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/glacier/event"
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/scheduler"
+	"github.com/mylxsw/go-ioc"
+	"github.com/robfig/cron/v3"
+)
+
+func createScheduler() (scheduler.Scheduler, *cron.Cron) {
+	return createSchedulerWith(scheduler.NewManager)
+}
+
+// createSchedulerWith create a scheduler by builder, bindings (like an event.Publisher) are registered
+// to the container before the scheduler is created
+func createSchedulerWith(builder func(resolver infra.Resolver) scheduler.Scheduler, bindings ...interface{}) (scheduler.Scheduler, *cron.Cron) {
+	cr := cron.New(cron.WithSeconds())
+	cc := ioc.New()
+	cc.MustSingleton(func() *cron.Cron { return cr })
+	cc.MustSingleton(func() infra.Resolver { return cc })
+	for _, binding := range bindings {
+		cc.MustSingleton(binding)
+	}
+
+	return builder(cc), cr
+}
+
+// createFakeClockScheduler create a scheduler driven by a FakeClock, the jobs are usually executed by Replay
+func createFakeClockScheduler(bindings ...interface{}) (scheduler.Scheduler, *scheduler.FakeClock) {
+	s, _ := createSchedulerWith(scheduler.NewManager, bindings...)
+	clock := scheduler.NewFakeClock(time.Now())
+	s.SetClock(clock)
+
+	return s, clock
+}
+
+func TestConcurrentLifecycle(t *testing.T) {
+	testConcurrentLifecycle(t, scheduler.NewManager)
+}
+
+func TestSerialConcurrentLifecycle(t *testing.T) {
+	testConcurrentLifecycle(t, scheduler.NewSerialManager)
+}
+
+func testConcurrentLifecycle(t *testing.T, builder func(resolver infra.Resolver) scheduler.Scheduler) {
+	s, cr := createSchedulerWith(builder)
+	s.Start()
+	defer s.Stop()
+
+	names := []string{"job-1", "job-2"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			r := rand.New(rand.NewSource(int64(i)))
+			for j := 0; j < 500; j++ {
+				name := names[r.Intn(len(names))]
+				switch r.Intn(5) {
+				case 0:
+					_ = s.Add(name, "@every 1h", func() {})
+				case 1:
+					_ = s.Pause(name)
+				case 2:
+					_ = s.Continue(name)
+				case 3:
+					_ = s.Remove(name)
+				case 4:
+					_, _ = s.Info(name)
+					_ = s.List()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// every non-paused job must have exactly one cron entry, and no entry exists for removed or paused jobs
+	entries := make(map[cron.EntryID]bool)
+	for _, entry := range cr.Entries() {
+		entries[entry.ID] = true
+	}
+
+	activeCount := 0
+	for _, job := range s.List() {
+		if job.Paused {
+			continue
+		}
+
+		activeCount++
+		if !entries[job.ID] {
+			t.Errorf("job %s has no cron entry", job.Name)
+		}
+	}
+
+	if activeCount != len(entries) {
+		t.Errorf("expect %d cron entries, got %d", activeCount, len(entries))
+	}
+
+	for _, name := range names {
+		_ = s.Pause(name)
+		_ = s.Remove(name)
+		if _, err := s.Info(name); err == nil {
+			t.Errorf("job %s should be removed", name)
+		}
+	}
+
+	if count := len(cr.Entries()); count != 0 {
+		t.Errorf("expect no cron entries after all jobs removed, got %d", count)
+	}
+}
+
+func TestLockRefreshWithLockManagerSetAfterStart(t *testing.T) {
+	s, cr := createScheduler()
+	s.MustAdd("job-1", "@every 1h", func() {})
+	s.MustAdd("job-2", "@every 1h", func() {})
+
+	s.Start()
+	defer s.Stop()
+
+	if count := s.EntryCount(); count != 2 {
+		t.Errorf("expect 2 cron entries without lock manager, got %d", count)
+	}
+
+	// setting a lock manager after start adds the lock refresh task, as Start does
+	s.LockManagerBuilder(func(name string) scheduler.LockManager { return nopLockManager{} })
+	if count := len(cr.Entries()); count != 3 {
+		t.Errorf("expect 3 cron entries after lock manager is set, got %d", count)
+	}
+
+	internal := s.ListInternal()
+	if len(internal) != 1 || internal[0].Name != "glacier:lock-refresh" {
+		t.Errorf("lock refresh task should be added, got %v", internal)
+	}
+
+	if err := s.CheckConsistency(); err != nil {
+		t.Error(err)
+	}
+}
+
+type nopLockManager struct{}
+
+func (nopLockManager) TryLock(ctx context.Context) error { return nil }
+func (nopLockManager) Release(ctx context.Context) error { return nil }
+
+type switchLockManager struct {
+	lock sync.Mutex
+	err  error
+}
+
+func (m *switchLockManager) TryLock(ctx context.Context) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.err
+}
+
+func (m *switchLockManager) fail(err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.err = err
+}
+
+func (m *switchLockManager) Release(ctx context.Context) error { return nil }
+
+func TestLockRefreshInterval(t *testing.T) {
+	s, _ := createScheduler()
+	s.LockManagerBuilder(func(name string) scheduler.LockManager { return nopLockManager{} })
+	s.SetLockRefreshInterval(10 * time.Second)
+
+	s.Start()
+	defer s.Stop()
+
+	refreshPlan := func() string {
+		for _, job := range s.ListInternal() {
+			if job.Name == "glacier:lock-refresh" {
+				return job.Plan
+			}
+		}
+
+		return ""
+	}
+
+	if plan := refreshPlan(); plan != "@every 10s" {
+		t.Errorf("lock refresh task should use the configured interval, got %q", plan)
+	}
+
+	s.SetLockRefreshInterval(20 * time.Second)
+	if plan := refreshPlan(); plan != "@every 20s" {
+		t.Errorf("lock refresh task should be rescheduled, got %q", plan)
+	}
+}
+
+func TestInternalTasksWithoutDescriptor(t *testing.T) {
+	s, _ := createScheduler()
+	s.SetParserOptions(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	s.LockManagerBuilder(func(name string) scheduler.LockManager { return nopLockManager{} })
+	s.SetHeartbeat(time.Hour, false)
+
+	if err := s.Add("descriptor", "@every 1h", func() {}); err == nil {
+		t.Error("descriptor should be rejected when cron.Descriptor is not in parser options")
+	}
+
+	s.Start()
+	defer s.Stop()
+
+	names := make(map[string]bool)
+	for _, job := range s.ListInternal() {
+		names[job.Name] = true
+	}
+
+	for _, name := range []string{"glacier:heartbeat", "glacier:lock-refresh"} {
+		if !names[name] {
+			t.Errorf("internal task %s should be scheduled regardless of parser options", name)
+		}
+	}
+
+	s.SetLockRefreshInterval(20 * time.Second)
+	if err := s.CheckConsistency(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	s, _ := createScheduler()
+	s.MustAdd("kept", "@every 1h", func() {})
+	s.MustAdd("replanned", "@every 1h", func() {})
+	s.MustAdd("removed", "@every 1h", func() {})
+
+	// the last job is invalid, it's rejected before the other changes are applied
+	_, err := s.Reconcile([]scheduler.JobConfig{
+		{Name: "kept", Plan: "@every 1h", Handler: func() {}},
+		{Name: "replanned", Plan: "@every 2h", Handler: func() {}},
+		{Name: "added", Plan: "@every 1h", Handler: func() {}},
+		{Name: "invalid", Plan: "@every 1h", Handler: func() {}, Options: []scheduler.JobOption{scheduler.WithRunLock()}},
+	})
+	if err == nil {
+		t.Fatal("reconcile should fail with an invalid job")
+	}
+
+	for name, existed := range map[string]bool{"kept": true, "replanned": true, "removed": true, "added": false, "invalid": false} {
+		if hasJob(s, name) != existed {
+			t.Errorf("failed reconcile should not change the scheduler, job %s existed: %v", name, !existed)
+		}
+	}
+
+	if job, _ := s.Info("replanned"); job.Plan != "@every 1h" {
+		t.Errorf("failed reconcile should not reschedule jobs, got %s", job.Plan)
+	}
+
+	result, err := s.Reconcile([]scheduler.JobConfig{
+		{Name: "kept", Plan: "@every 1h", Handler: func() {}},
+		{Name: "replanned", Plan: "@every 2h", Handler: func() {}},
+		{Name: "added", Plan: "@every 1h", Handler: func() {}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := scheduler.ReconcileResult{Added: []string{"added"}, Removed: []string{"removed"}, Rescheduled: []string{"replanned"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expect %+v, got %+v", expected, result)
+	}
+
+	if err := s.CheckConsistency(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReconcileJobsWithoutSpec(t *testing.T) {
+	s, _ := createScheduler()
+	next := func(last time.Time) (time.Time, error) { return time.Now().Add(time.Hour), nil }
+
+	s.MustAdd("spec", "@every 1h", func() {})
+	if err := s.AddDynamic("dynamic", next, func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AddDynamic("undesired-dynamic", next, func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RunAt("once", time.Now().Add(time.Hour), func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Reconcile([]scheduler.JobConfig{
+		{Name: "spec", Plan: "@every 2h", Handler: func() {}},
+		{Name: "dynamic", Plan: scheduler.DynamicPlan, Handler: func() {}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := scheduler.ReconcileResult{Added: []string{}, Removed: []string{}, Rescheduled: []string{"spec"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expect %+v, got %+v", expected, result)
+	}
+
+	for _, name := range []string{"dynamic", "undesired-dynamic", "once"} {
+		if job, err := s.Info(name); err != nil || job.Plan == "@every 2h" {
+			t.Errorf("job %s without a cron spec should be untouched, got %+v, %v", name, job, err)
+		}
+	}
+
+	if err := s.CheckConsistency(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMinIntervalWhileRunning(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	s.MustAdd("slow", "@every 1h", func() {
+		started <- struct{}{}
+		<-release
+	}, scheduler.WithMinInterval(time.Minute))
+
+	skipReason := func() string {
+		job, _ := s.Info("slow")
+		return job.LastSkipReason
+	}
+
+	s.MustTrigger("slow")
+	<-started
+
+	// the execution in progress has reserved the slot, the concurrent one is too soon
+	s.MustTrigger("slow")
+	deadline := time.Now().Add(3 * time.Second)
+	for skipReason() != scheduler.SkipReasonMinInterval && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if reason := skipReason(); reason != scheduler.SkipReasonMinInterval {
+		t.Fatalf("execution should be skipped while another one is running, got %q", reason)
+	}
+
+	close(release)
+	deadline = time.Now().Add(3 * time.Second)
+	for job, _ := s.Info("slow"); job.Running && time.Now().Before(deadline); job, _ = s.Info("slow") {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	clock.Advance(time.Minute)
+	s.MustTrigger("slow")
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("job should run again after the min interval")
+	}
+}
+
+func TestHeartbeatRestart(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+	s.SetHeartbeat(time.Hour, false)
+
+	s.Start()
+	s.Stop()
+
+	restartedAt := clock.Now().Add(time.Minute)
+	clock.Set(restartedAt)
+
+	s.Start()
+	defer s.Stop()
+
+	if internal := s.ListInternal(); len(internal) != 1 || internal[0].Name != "glacier:heartbeat" {
+		t.Errorf("heartbeat task should be kept after restart, got %+v", internal)
+	}
+
+	if !s.LastHeartbeat().Equal(restartedAt) {
+		t.Errorf("heartbeat should beat on restart, got %s", s.LastHeartbeat())
+	}
+}
+
+func TestInternalJobsProtected(t *testing.T) {
+	s, cr := createScheduler()
+	s.SetHeartbeat(time.Hour, false)
+	s.Start()
+	defer s.Stop()
+
+	const name = "glacier:heartbeat"
+	operations := map[string]func() error{
+		"remove":      func() error { return s.Remove(name) },
+		"pause":       func() error { return s.Pause(name) },
+		"pause until": func() error { return s.PauseUntil(name, time.Now().Add(time.Hour)) },
+		"continue":    func() error { return s.Continue(name) },
+		"update plan": func() error { return s.UpdatePlan(name, "@every 1m") },
+		"trigger":     func() error { return s.Trigger(name) },
+	}
+
+	for op, fn := range operations {
+		if err := fn(); !errors.Is(err, scheduler.ErrInternalJob) {
+			t.Errorf("%s should be rejected for internal job, got %v", op, err)
+		}
+	}
+
+	internal := s.ListInternal()
+	if len(internal) != 1 || internal[0].Plan != "@every 1h0m0s" || internal[0].Paused || len(cr.Entries()) != 1 {
+		t.Errorf("internal job should be untouched, got %+v", internal)
+	}
+
+	if err := s.AddOrReplace(name, "@every 1m", func() {}); !errors.Is(err, scheduler.ErrInternalJob) {
+		t.Errorf("internal job should not be replaced, got %v", err)
+	}
+
+	for _, reserved := range []string{name, "glacier:custom"} {
+		if err := s.Add(reserved, "@every 1m", func() {}); !errors.Is(err, scheduler.ErrInternalJob) {
+			t.Errorf("name %s with the reserved prefix should be rejected, got %v", reserved, err)
+		}
+	}
+
+	if _, err := s.Reconcile([]scheduler.JobConfig{{Name: "glacier:custom", Plan: "@every 1m", Handler: func() {}}}); !errors.Is(err, scheduler.ErrInternalJob) {
+		t.Errorf("reconcile should reject the reserved prefix, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	scheduler.AdminHandler(s).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/jobs/"+name, nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("admin should not remove internal job, got status %d", w.Code)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	s, _ := createScheduler()
+
+	for _, labels := range []map[string]string{{"job": "x"}, {"le": "1"}, {"1env": "prod"}, {"__name": "x"}, {"env-name": "prod"}} {
+		if err := s.SetLabels(labels); err == nil {
+			t.Errorf("labels %v should be rejected", labels)
+		}
+	}
+
+	if err := s.SetLabels(map[string]string{"env": "prod", "service": "billing"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.MustAdd("job", "@every 1h", func() {})
+
+	var buf bytes.Buffer
+	if err := s.WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `glacier_scheduler_job_runs_total{env="prod",service="billing",job="job"} 0`) {
+		t.Errorf("labels should be added to metrics, got:\n%s", buf.String())
+	}
+}
+
+func TestOnLockLost(t *testing.T) {
+	s, _ := createScheduler()
+
+	lockManager := &switchLockManager{}
+	s.LockManagerBuilder(func(name string) scheduler.LockManager { return lockManager })
+
+	lost := make(chan string, 1)
+	s.OnLockLost(func(name string) { lost <- name })
+
+	started, cancelled := make(chan struct{}), make(chan error, 1)
+	s.MustAdd("leader", "@every 1h", func(ctx context.Context) {
+		select {
+		case started <- struct{}{}:
+		default:
+			return
+		}
+
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+	})
+
+	s.MustTrigger("leader")
+	<-started
+
+	// errors other than ErrLockFailed can not tell whether the lock is held, the lock is not lost
+	lockManager.fail(errors.New("connection refused"))
+	s.MustTrigger("leader")
+
+	select {
+	case <-lost:
+		t.Fatal("lock should not be lost by a transient error")
+	case <-cancelled:
+		t.Fatal("running execution should not be cancelled by a transient error")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the next execution can not get the lock, the lock held by the running one is lost
+	lockManager.fail(scheduler.ErrLockFailed)
+	s.MustTrigger("leader")
+
+	select {
+	case name := <-lost:
+		if name != "leader" {
+			t.Errorf("expect lock of [leader] lost, got %s", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lock lost handler should be called")
+	}
+
+	select {
+	case err := <-cancelled:
+		if err != context.Canceled {
+			t.Errorf("running execution should be cancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("running execution should be cancelled when lock is lost")
+	}
+}
+
+func TestSelfModifyingJob(t *testing.T) {
+	testSelfModifyingJob(t, scheduler.NewManager)
+}
+
+func TestSerialSelfModifyingJob(t *testing.T) {
+	testSelfModifyingJob(t, scheduler.NewSerialManager)
+}
+
+func testSelfModifyingJob(t *testing.T, builder func(resolver infra.Resolver) scheduler.Scheduler) {
+	cc := ioc.New()
+	cc.MustSingleton(func() *cron.Cron { return cron.New(cron.WithSeconds()) })
+	cc.MustSingleton(func() infra.Resolver { return cc })
+	cc.MustSingleton(builder)
+
+	var s scheduler.Scheduler
+	cc.MustResolve(func(sc scheduler.Scheduler) { s = sc })
+
+	clock := scheduler.NewFakeClock(time.Now())
+	s.SetClock(clock)
+
+	s.MustAdd("control", "@every 1h", func(sc scheduler.Scheduler) error {
+		if err := sc.Add("child", "@every 1h", func() {}); err != nil {
+			return err
+		}
+
+		return sc.Remove("control")
+	})
+
+	if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: "control", ActualStart: clock.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Info("child"); err != nil {
+		t.Errorf("child job should be added by control job: %v", err)
+	}
+
+	if _, err := s.Info("control"); err == nil {
+		t.Error("control job should be removed by itself")
+	}
+}
+
+func TestStatsOfConcurrentRuns(t *testing.T) {
+	s, _ := createScheduler()
+
+	release := make(chan struct{})
+	s.MustAdd("busy", "@every 1h", func() error {
+		<-release
+		return errors.New("failed")
+	})
+
+	s.Start()
+	for i := 0; i < 50; i++ {
+		s.MustTrigger("busy")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if job, _ := s.Info("busy"); !job.Running || job.Stats.Running != 50 {
+		t.Errorf("all executions should be running: %+v", job.Stats)
+	}
+
+	close(release)
+	s.Stop()
+
+	job, _ := s.Info("busy")
+	if job.Running || job.Stats.Running != 0 || job.Stats.RunCount != 50 || job.Stats.FailureCount != 50 || job.Stats.ConsecutiveFailures != 50 {
+		t.Errorf("every execution should be counted: %+v", job.Stats)
+	}
+
+	if stats := s.Stats(); stats.TotalRuns != 50 || stats.FailingJobs != 1 {
+		t.Errorf("aggregate statistics should match the job: %+v", stats)
+	}
+}
+
+func BenchmarkInfoUnderLoad(b *testing.B) {
+	s, clock := createFakeClockScheduler()
+
+	records := make([]scheduler.ScheduleRecord, 0, 100)
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("job-%d", i)
+		s.MustAdd(name, "@every 1h", func() {})
+		records = append(records, scheduler.ScheduleRecord{Name: name, ActualStart: clock.Now()})
+	}
+
+	// keep executing jobs in background, every execution updates the job stats
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					_ = scheduler.Replay(s, clock, records)
+				}
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = s.Info("job-50")
+		}
+	})
+	b.StopTimer()
+
+	close(stop)
+	wg.Wait()
+}
+
+type recordingLockManager struct {
+	events *eventLog
+}
+
+func (m recordingLockManager) TryLock(ctx context.Context) error { return nil }
+func (m recordingLockManager) Release(ctx context.Context) error {
+	m.events.add("released")
+	return nil
+}
+
+type eventLog struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(evt string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.events = append(l.events, evt)
+}
+
+func (l *eventLog) list() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return append([]string{}, l.events...)
+}
+
+func TestStopWaitsForRunningJobs(t *testing.T) {
+	s, _ := createScheduler()
+
+	events := &eventLog{}
+	s.LockManagerBuilder(func(name string) scheduler.LockManager { return recordingLockManager{events: events} })
+
+	started := make(chan struct{}, 1)
+	s.MustAdd("slow", "@every 1s", func() {
+		select {
+		case started <- struct{}{}:
+		default:
+			return
+		}
+
+		time.Sleep(300 * time.Millisecond)
+		events.add("finished")
+	})
+
+	s.Start()
+	<-started
+	s.Stop()
+	events.add("stopped")
+
+	if got := fmt.Sprint(events.list()); got != "[finished released stopped]" {
+		t.Errorf("Stop should wait for the running job before releasing locks, got %s", got)
+	}
+}
+
+func TestStopWaitsForTriggeredJobs(t *testing.T) {
+	for _, stop := range []func(s scheduler.Scheduler){scheduler.Scheduler.Stop, scheduler.Scheduler.PrepareForShutdown} {
+		s, _ := createScheduler()
+
+		events := &eventLog{}
+		s.LockManagerBuilder(func(name string) scheduler.LockManager { return recordingLockManager{events: events} })
+
+		started := make(chan struct{})
+		s.MustAdd("manual", "@every 1h", func() {
+			close(started)
+			time.Sleep(300 * time.Millisecond)
+			events.add("finished")
+		})
+
+		s.Start()
+		s.MustTrigger("manual")
+		<-started
+		stop(s)
+		events.add("stopped")
+
+		if got := fmt.Sprint(events.list()); got != "[finished released stopped]" {
+			t.Errorf("shutdown should wait for the triggered job before releasing locks, got %s", got)
+		}
+	}
+}
+
+type countingLockManager struct {
+	tries *int64
+}
+
+func (m countingLockManager) TryLock(ctx context.Context) error {
+	atomic.AddInt64(m.tries, 1)
+	return nil
+}
+
+func (m countingLockManager) Release(ctx context.Context) error { return nil }
+
+func TestStopAbandonsJitteredExecution(t *testing.T) {
+	s, _ := createScheduler()
+
+	var tries, runs int64
+	s.LockManagerBuilder(func(name string) scheduler.LockManager { return countingLockManager{tries: &tries} })
+	s.MustAdd("jittered", "@every 1s", func() { atomic.AddInt64(&runs, 1) }, scheduler.WithJitter(time.Hour))
+
+	s.Start()
+	time.Sleep(1300 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop should not wait for the execution delayed by jitter")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt64(&tries) != 0 || atomic.LoadInt64(&runs) != 0 {
+		t.Errorf("delayed execution should be abandoned after Stop, lock tried %d times, ran %d times", tries, runs)
+	}
+}
+
+func TestStopAbandonsSpreadExecution(t *testing.T) {
+	s, _ := createScheduler()
+	s.SetTickSpread(time.Hour)
+
+	var firstRuns, secondRuns int64
+	s.MustAdd("first", "@every 1s", func() { atomic.AddInt64(&firstRuns, 1) })
+	s.MustAdd("second", "@every 1s", func() { atomic.AddInt64(&secondRuns, 1) })
+
+	s.Start()
+	time.Sleep(1300 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop should not wait for the execution delayed by tick spread")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt64(&firstRuns) == 0 || atomic.LoadInt64(&secondRuns) != 0 {
+		t.Errorf("only the first job in tick should run, the delayed one should be abandoned, got %d, %d", firstRuns, secondRuns)
+	}
+}
+
+func TestTickSpread(t *testing.T) {
+	s, _ := createScheduler()
+	testTickSpread(t, s)
+}
+
+func TestTickSpreadWithClock(t *testing.T) {
+	// the jobs are spread by the instant they are scheduled at, not the time of scheduler clock
+	s, clock := createFakeClockScheduler()
+	clock.Set(time.Now().Add(-time.Hour - 500*time.Millisecond))
+
+	testTickSpread(t, s)
+}
+
+func testTickSpread(t *testing.T, s scheduler.Scheduler) {
+	s.SetTickSpread(900 * time.Millisecond)
+
+	var lock sync.Mutex
+	firstRuns := make(map[string]time.Time)
+	names := []string{"first", "second", "third"}
+	for _, name := range names {
+		name := name
+		s.MustAdd(name, "@every 1s", func() {
+			lock.Lock()
+			defer lock.Unlock()
+
+			if _, ok := firstRuns[name]; !ok {
+				firstRuns[name] = time.Now()
+			}
+		})
+	}
+
+	s.Start()
+	time.Sleep(2 * time.Second)
+	s.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(firstRuns) != len(names) {
+		t.Fatalf("all jobs should run, got %v", firstRuns)
+	}
+
+	// the i-th job is delayed by 300ms * i
+	for i := 1; i < len(names); i++ {
+		if gap := firstRuns[names[i]].Sub(firstRuns[names[i-1]]); gap < 200*time.Millisecond || gap > 400*time.Millisecond {
+			t.Errorf("job [%s] should run about 300ms after [%s], got %s", names[i], names[i-1], gap)
+		}
+	}
+}
+
+func TestStopWithTimeoutDetaches(t *testing.T) {
+	s, _ := createScheduler()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+
+	s.MustAdd("blocking", "@every 1h", func() {
+		close(started)
+		<-release
+	})
+
+	s.Start()
+	s.MustTrigger("blocking")
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		s.StopWithTimeout(200 * time.Millisecond)
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("StopWithTimeout should stop waiting for the blocking execution after timeout")
+	}
+
+	if job, _ := s.Info("blocking"); !job.Running {
+		t.Errorf("the detached execution should be still running: %+v", job)
+	}
+}
+
+func TestRunWithoutEventPublisher(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+	s.SetHeartbeat(time.Hour, true)
+
+	executed := false
+	s.MustAdd("job", "@every 1h", func() { executed = true })
+	// this job is always skipped, and publishes a JobSkippedEvent on every schedule
+	s.MustAdd("skipped", "@every 1h", func() {}, scheduler.WithActiveHours(nil, "00:00", "00:00", nil))
+
+	s.Start()
+	defer s.Stop()
+
+	records := []scheduler.ScheduleRecord{{Name: "job", ActualStart: clock.Now()}, {Name: "skipped", ActualStart: clock.Now()}}
+	if err := scheduler.Replay(s, clock, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if !executed {
+		t.Error("job should be executed without event publisher")
+	}
+
+	if job, _ := s.Info("job"); job.Stats.RunCount != 1 || job.Stats.FailureCount != 0 {
+		t.Errorf("unexpected stats: %+v", job.Stats)
+	}
+
+	if job, _ := s.Info("skipped"); job.LastSkipReason != scheduler.SkipReasonInactive {
+		t.Errorf("unexpected skip reason: %s", job.LastSkipReason)
+	}
+
+	if s.LastHeartbeat().IsZero() {
+		t.Error("heartbeat should work without event publisher")
+	}
+}
+
+type memoryRecorder struct {
+	lock    sync.Mutex
+	records []scheduler.ScheduleRecord
+}
+
+func (r *memoryRecorder) Record(rec scheduler.ScheduleRecord) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.records = append(r.records, rec)
+	return nil
+}
+
+func (r *memoryRecorder) all() []scheduler.ScheduleRecord {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]scheduler.ScheduleRecord{}, r.records...)
+}
+
+func TestRecordScheduledTime(t *testing.T) {
+	s, _ := createScheduler()
+	recorder := &memoryRecorder{}
+	s.SetRecorder(recorder)
+
+	s.MustAdd("jittered", "@every 1s", func() {}, scheduler.WithJitter(800*time.Millisecond))
+	s.MustAdd("manual", "@every 1h", func() {})
+
+	s.Start()
+	defer s.Stop()
+
+	s.MustTrigger("manual")
+	time.Sleep(2500 * time.Millisecond)
+
+	var jittered, manual int
+	for _, rec := range recorder.all() {
+		switch rec.Name {
+		case "jittered":
+			jittered++
+			if rec.ScheduledTime.Nanosecond() != 0 || rec.ScheduledTime.After(rec.ActualStart) || rec.ActualStart.Sub(rec.ScheduledTime) > time.Second {
+				t.Errorf("scheduled time should be the time planned by cron: %+v", rec)
+			}
+		case "manual":
+			manual++
+			if !rec.ScheduledTime.Equal(rec.ActualStart) {
+				t.Errorf("scheduled time of manual execution should be the actual start time: %+v", rec)
+			}
+		}
+	}
+
+	if jittered == 0 || manual != 1 {
+		t.Errorf("unexpected records: %+v", recorder.all())
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	released := make(chan struct{})
+	s.MustAdd("hang", "@every 1h", func(ctx context.Context) {
+		<-ctx.Done()
+		close(released)
+		time.Sleep(time.Hour)
+	}, scheduler.WithTimeout(10*time.Millisecond))
+	s.MustAdd("plain", "@every 1h", func() {}, scheduler.WithTimeout(time.Second))
+
+	records := []scheduler.ScheduleRecord{{Name: "hang", ActualStart: clock.Now()}, {Name: "plain", ActualStart: clock.Now()}}
+	if err := scheduler.Replay(s, clock, records); err != nil {
+		t.Fatal(err)
+	}
+
+	<-released
+	if job, _ := s.Info("hang"); job.Stats.FailureCount != 1 || job.Stats.LastError == "" || job.Running {
+		t.Errorf("timeout job should be recorded as failed: %+v", job.Stats)
+	}
+
+	if job, _ := s.Info("plain"); job.Stats.RunCount != 1 || job.Stats.FailureCount != 0 {
+		t.Errorf("unexpected stats: %+v", job.Stats)
+	}
+}
+
+func TestTimeoutKeepsLocalGuards(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+	s.SetRunScope(1)
+
+	var cleaned int32
+	release := make(chan struct{})
+	s.MustAdd("hang", "@every 1h", func(scope *scheduler.RunScope) {
+		scope.Defer(func() error {
+			atomic.AddInt32(&cleaned, 1)
+			return nil
+		})
+
+		<-release
+	}, scheduler.WithTimeout(20*time.Millisecond), scheduler.WithSkipIfRunning(), scheduler.WithMutexGroup("group"))
+	s.MustAdd("peer", "@every 1h", func() {}, scheduler.WithMutexGroup("group"))
+
+	replay := func(name string) {
+		if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: name, ActualStart: clock.Now()}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replay("hang")
+	if job, _ := s.Info("hang"); job.Stats.FailureCount != 1 {
+		t.Fatalf("execution should time out: %+v", job.Stats)
+	}
+
+	if atomic.LoadInt32(&cleaned) != 0 {
+		t.Error("run scope should not be released while the handler is running")
+	}
+
+	replay("hang")
+	if job, _ := s.Info("hang"); job.LastSkipReason != scheduler.SkipReasonRunning {
+		t.Errorf("the next execution should be skipped while the timed out handler is running: %+v", job)
+	}
+
+	replay("peer")
+	if job, _ := s.Info("peer"); job.LastSkipReason != scheduler.SkipReasonMutexGroup {
+		t.Errorf("mutex group should be held while the timed out handler is running: %+v", job)
+	}
+
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+
+	if atomic.LoadInt32(&cleaned) != 1 {
+		t.Error("run scope should be released after the handler returns")
+	}
+
+	replay("peer")
+	if job, _ := s.Info("peer"); job.Stats.RunCount != 1 {
+		t.Errorf("mutex group should be released after the handler returns: %+v", job)
+	}
+}
+
+func TestJobLocation(t *testing.T) {
+	s, _ := createScheduler()
+
+	loc := time.FixedZone("UTC-5", -5*3600)
+	s.MustAdd("located", "0 0 9 * * *", func() {}, scheduler.WithLocation(loc))
+	s.MustAdd("prefixed", "CRON_TZ=UTC 0 0 9 * * *", func() {}, scheduler.WithLocation(loc))
+
+	for name, want := range map[string]*time.Location{"located": loc, "prefixed": time.UTC} {
+		job, _ := s.Info(name)
+		nexts, err := job.Next(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if next := nexts[0].In(want); next.Hour() != 9 || next.Minute() != 0 {
+			t.Errorf("job [%s] should run at 09:00 in %s, got %s", name, want, next)
+		}
+	}
+}
+
+func TestParserOptions(t *testing.T) {
+	s, cr := createScheduler()
+	s.SetParserOptions(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+	s.MustAdd("standard", "30 9 * * *", func() {})
+	s.MustAdd("seconds", "15 30 9 * * *", func() {})
+
+	for name, second := range map[string]int{"standard": 0, "seconds": 15} {
+		job, _ := s.Info(name)
+		nexts, err := job.Next(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if next := nexts[0]; next.Hour() != 9 || next.Minute() != 30 || next.Second() != second {
+			t.Errorf("job [%s] should run at 09:30:%02d, got %s", name, second, next)
+		}
+	}
+
+	if len(cr.Entries()) != 2 {
+		t.Errorf("both jobs should be scheduled, got %d entries", len(cr.Entries()))
+	}
+}
+
+func TestDescribePlan(t *testing.T) {
+	s, _ := createScheduler()
+
+	if err := s.ValidatePlan("* * *"); err == nil {
+		t.Error("invalid plan should be rejected")
+	}
+
+	for plan, want := range map[string]string{
+		"@every 90s":              "every 1m30s",
+		"@daily":                  "at 00:00:00 every day",
+		"0 30 9 * * MON-FRI":      "at 09:30:00, day-of-week Mon-Fri",
+		"0 */15 * 1,15 * *":       "second 0, minute 0,15,30,45, every hour, day-of-month 1,15",
+		"CRON_TZ=UTC 0 0 8 * * *": "at 08:00:00 every day, in UTC",
+	} {
+		if err := s.ValidatePlan(plan); err != nil {
+			t.Errorf("plan [%s] should be valid: %v", plan, err)
+		}
+
+		if desc, _ := s.Describe(plan); desc != want {
+			t.Errorf("plan [%s] should be described as [%s], got [%s]", plan, want, desc)
+		}
+	}
+}
+
+func TestUpdatePlan(t *testing.T) {
+	s, cr := createScheduler()
+
+	s.MustAdd("job", "@every 1h", func() {})
+	if err := s.UpdatePlan("job", "invalid plan"); err == nil {
+		t.Fatal("invalid plan should be rejected")
+	}
+
+	if err := s.Pause("job"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.UpdatePlan("job", "@every 2h"); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, _ := s.Info("job"); !job.Paused || job.Plan != "@every 2h" || len(cr.Entries()) != 0 {
+		t.Errorf("paused job should keep paused with new plan: %+v", job)
+	}
+
+	if err := s.Continue("job"); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries := cr.Entries(); len(entries) != 1 || entries[0].Schedule.(cron.ConstantDelaySchedule).Delay != 2*time.Hour {
+		t.Errorf("job should be scheduled with new plan")
+	}
+}
+
+func TestJobRetry(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	attempts := 0
+	s.MustAdd("retry", "@every 1h", func() error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("attempt %d failed", attempts)
+		}
+
+		return nil
+	}, scheduler.WithRetry(3, time.Millisecond))
+
+	if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: "retry", ActualStart: clock.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, _ := s.Info("retry"); attempts != 3 || job.Stats.RunCount != 1 || job.Stats.FailureCount != 0 {
+		t.Errorf("job should succeed after 2 retries, attempts: %d, stats: %+v", attempts, job.Stats)
+	}
+}
+
+func TestRetryStopsWhenCancelled(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	attempts := 0
+	s.MustAdd("cancelled", "@every 1h", func(ctx context.Context) error {
+		attempts++
+		go s.Stop()
+		<-ctx.Done()
+
+		return fmt.Errorf("attempt %d failed", attempts)
+	}, scheduler.WithRetry(3, time.Hour))
+
+	if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: "cancelled", ActualStart: clock.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 1 {
+		t.Errorf("no more retry should be made after the run is cancelled, attempts: %d", attempts)
+	}
+}
+
+func TestJobResult(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	s.MustAdd("sync", "@every 1h", func() (int, error) { return 4210, nil })
+	s.MustAdd("broken", "@every 1h", func() (int, error) { return 0, fmt.Errorf("sync failed") })
+
+	records := []scheduler.ScheduleRecord{{Name: "sync", ActualStart: clock.Now()}, {Name: "broken", ActualStart: clock.Now()}}
+	if err := scheduler.Replay(s, clock, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, _ := s.Info("sync"); job.Stats.LastResult != 4210 {
+		t.Errorf("the result of handler should be captured, got %v", job.Stats.LastResult)
+	}
+
+	if job, _ := s.Info("broken"); job.Stats.FailureCount != 1 || job.Stats.LastError == "" {
+		t.Errorf("the trailing error of handler should fail the execution, stats: %+v", job.Stats)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	type spanKey struct{}
+	var spans []string
+	var spanErrs []error
+	s.SetTracer(func(ctx context.Context, name string) (context.Context, func(err error)) {
+		spans = append(spans, name)
+		return context.WithValue(ctx, spanKey{}, name), func(err error) { spanErrs = append(spanErrs, err) }
+	})
+
+	var runIDs []string
+	s.MustAdd("traced", "@every 1h", func(ctx context.Context) error {
+		if ctx.Value(spanKey{}) != "traced" {
+			t.Error("handler should receive the context returned by tracer")
+		}
+
+		runIDs = append(runIDs, scheduler.RunIDFromContext(ctx))
+		if len(runIDs) == 1 {
+			return fmt.Errorf("first attempt failed")
+		}
+
+		return nil
+	}, scheduler.WithRetry(1, time.Millisecond))
+
+	if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: "traced", ActualStart: clock.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spans) != 2 || spanErrs[0] == nil || spanErrs[1] != nil {
+		t.Errorf("every attempt should be traced with its result, spans: %v, errors: %v", spans, spanErrs)
+	}
+
+	if len(runIDs) != 2 || runIDs[0] == "" || runIDs[0] != runIDs[1] {
+		t.Errorf("attempts of the same run should share a run id, got %v", runIDs)
+	}
+}
+
+func TestTracerRecordsPanic(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	var spanErrs []error
+	s.SetTracer(func(ctx context.Context, name string) (context.Context, func(err error)) {
+		return ctx, func(err error) { spanErrs = append(spanErrs, err) }
+	})
+
+	s.MustAdd("panic", "@every 1h", func() { panic("boom") })
+
+	if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: "panic", ActualStart: clock.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spanErrs) != 1 || spanErrs[0] == nil || !strings.Contains(spanErrs[0].Error(), "boom") {
+		t.Errorf("the span of a panicking attempt should be finished with the panic, got %v", spanErrs)
+	}
+
+	if job, _ := s.Info("panic"); job.Stats.FailureCount != 1 {
+		t.Errorf("panic should be counted as a failure, got %+v", job.Stats)
+	}
+}
+
+func TestPanicHandlerAndFormatter(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	var calls []string
+	s.SetPanicHandler(func(job scheduler.Job, recovered interface{}, stack []byte) {
+		calls = append(calls, "handler:"+job.Name)
+		panic("handler failed")
+	})
+	s.SetPanicFormatter(func(name string, recovered interface{}, stack []byte) error {
+		calls = append(calls, "formatter:"+name)
+		return fmt.Errorf("formatted: %v", recovered)
+	})
+
+	s.MustAdd("panic", "@every 1h", func() { panic("boom") })
+	if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: "panic", ActualStart: clock.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(calls, []string{"handler:panic", "formatter:panic"}) {
+		t.Errorf("panic handler should be called before formatter, got %v", calls)
+	}
+
+	if job, _ := s.Info("panic"); job.Stats.LastError != "formatted: boom" {
+		t.Errorf("error of the execution should be converted by formatter, got %+v", job.Stats)
+	}
+}
+
+func TestTimelineLimit(t *testing.T) {
+	s, _ := createScheduler()
+	clock := scheduler.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local))
+	s.SetClock(clock)
+
+	s.MustAdd("every-second", "@every 1s", func() {})
+	s.MustAdd("hourly", "0 30 * * * *", func() {})
+
+	if runs := s.Timeline(time.Hour, 0); len(runs) != scheduler.DefaultTimelineLimit {
+		t.Errorf("timeline should be limited to %d runs by default, got %d", scheduler.DefaultTimelineLimit, len(runs))
+	}
+
+	runs := s.Timeline(24*time.Hour, 3)
+	if len(runs) != 3 || runs[0].Name != "every-second" || !runs[2].At.Equal(clock.Now().Add(3*time.Second)) {
+		t.Errorf("the earliest runs should be returned, got %+v", runs)
+	}
+
+	// 3600 runs of every-second and 1 run of hourly
+	if runs = s.Timeline(time.Hour, 4000); len(runs) != 3601 {
+		t.Errorf("all runs within duration should be returned under limit, got %d", len(runs))
+	}
+}
+
+func TestDynamicJob(t *testing.T) {
+	s, cr := createScheduler()
+
+	var lock sync.Mutex
+	var failedCalls, runs int
+	var lasts []time.Time
+
+	if err := s.AddDynamic("failing", func(last time.Time) (time.Time, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		failedCalls++
+		return time.Time{}, fmt.Errorf("next time not available")
+	}, func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		runs++
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	first := time.Now().Add(200 * time.Millisecond)
+	if err := s.AddDynamic("twice", func(last time.Time) (time.Time, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		lasts = append(lasts, last)
+		switch len(lasts) {
+		case 1:
+			return first, nil
+		case 2:
+			return last.Add(300 * time.Millisecond), nil
+		default:
+			return time.Time{}, nil
+		}
+	}, func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	cr.Start()
+	time.Sleep(2500 * time.Millisecond)
+	cr.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if failedCalls < 2 || runs != 0 {
+		t.Errorf("handler should never run on probes, next called %d times, handler ran %d times", failedCalls, runs)
+	}
+
+	if len(lasts) != 3 || !lasts[0].IsZero() || !lasts[1].Equal(first) || !lasts[2].Equal(first.Add(300*time.Millisecond)) {
+		t.Errorf("last should be the scheduled time of the previous execution, got %v", lasts)
+	}
+}
+
+func TestRunAt(t *testing.T) {
+	s, cr := createScheduler()
+
+	at := time.Now().Add(100 * time.Millisecond)
+	executed := make(chan struct{}, 2)
+	if err := s.RunAt("once", at, func() { executed <- struct{}{} }); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := s.Info("once")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Plan != scheduler.OncePlan || !job.RunAt.Equal(at) {
+		t.Errorf("one-shot job should report its scheduled time, got %s | %s", job.Plan, job.RunAt)
+	}
+
+	cr.Start()
+	defer cr.Stop()
+
+	select {
+	case <-executed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("one-shot job should be executed")
+	}
+
+	waitForRemoval(t, s, "once")
+}
+
+func TestRunAtPausedAcrossTime(t *testing.T) {
+	s, cr := createScheduler()
+
+	executed := make(chan struct{}, 2)
+	if err := s.RunAt("once", time.Now().Add(200*time.Millisecond), func() { executed <- struct{}{} }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Pause("once"); err != nil {
+		t.Fatal(err)
+	}
+
+	cr.Start()
+	defer cr.Stop()
+
+	time.Sleep(500 * time.Millisecond)
+	if err := s.Continue("once"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-executed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("one-shot job whose time passed while paused should be executed after continued")
+	}
+
+	waitForRemoval(t, s, "once")
+}
+
+func TestRunAtSkipped(t *testing.T) {
+	s, cr := createScheduler()
+
+	release, holding := make(chan struct{}), make(chan struct{})
+	s.MustAdd("holder", "@every 1h", func() {
+		close(holding)
+		<-release
+	}, scheduler.WithMutexGroup("exclusive"))
+
+	var lock sync.Mutex
+	runs := 0
+	if err := s.RunAt("once", time.Now().Add(200*time.Millisecond), func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		runs++
+	}, scheduler.WithMutexGroup("exclusive")); err != nil {
+		t.Fatal(err)
+	}
+
+	s.MustTrigger("holder")
+	<-holding
+
+	cr.Start()
+	defer cr.Stop()
+
+	time.Sleep(500 * time.Millisecond)
+	if job, err := s.Info("once"); err != nil || job.LastSkipReason != scheduler.SkipReasonMutexGroup {
+		t.Fatalf("one-shot job should be kept after skipped, got %+v, %v", job, err)
+	}
+
+	close(release)
+	waitForRemoval(t, s, "once")
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if runs != 1 {
+		t.Errorf("skipped one-shot job should be executed once after retried, got %d", runs)
+	}
+}
+
+func waitForRemoval(t *testing.T, s scheduler.Scheduler, name string) {
+	deadline := time.Now().Add(3 * time.Second)
+	for hasJob(s, name) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if hasJob(s, name) {
+		t.Errorf("one-shot job [%s] should be removed after execution", name)
+	}
+}
+
+func TestInfoByID(t *testing.T) {
+	s, cr := createScheduler()
+
+	id, err := s.AddAndReturnID("bridged", "@every 1m", func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cr.Entry(id).ID != id {
+		t.Fatalf("the returned id should be the entry id in cron, got %d", id)
+	}
+
+	job, err := s.InfoByID(id)
+	if err != nil || job.Name != "bridged" {
+		t.Errorf("job should be found by its entry id, got %s, %v", job.Name, err)
+	}
+
+	if err := s.Pause("bridged"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.InfoByID(id); !errors.Is(err, scheduler.ErrJobNotFound) {
+		t.Errorf("paused job has no entry in cron, it should not be found by id, got %v", err)
+	}
+}
+
+func TestAddOrReplace(t *testing.T) {
+	s, _ := createScheduler()
+
+	if err := s.Add("reload", "@every 1m", func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Add("reload", "@every 2m", func() {}); err == nil {
+		t.Error("Add should fail when the job name exists")
+	}
+
+	if err := s.Pause("reload"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AddOrReplace("reload", "invalid plan", func() {}); err == nil {
+		t.Error("invalid plan should be rejected")
+	}
+
+	if job, err := s.Info("reload"); err != nil || job.Plan != "@every 1m" {
+		t.Errorf("the existing job should be untouched when replacement fails, got %s, %v", job.Plan, err)
+	}
+
+	if err := s.AddOrReplace("reload", "@every 2m", func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := s.Info("reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Plan != "@every 2m" || !job.Paused {
+		t.Errorf("the job should be replaced with its paused state kept, got %s | paused=%v", job.Plan, job.Paused)
+	}
+
+	if err := s.AddOrReplace("fresh", "@every 1m", func() {}); err != nil || !hasJob(s, "fresh") {
+		t.Errorf("AddOrReplace should add the job when it does not exist: %v", err)
+	}
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	em := event.NewEventManager(event.NewMemoryEventStore(false, 10))
+
+	var started []scheduler.SchedulerStartedEvent
+	var stopped []scheduler.SchedulerStoppedEvent
+	em.Listen(func(evt scheduler.SchedulerStartedEvent) { started = append(started, evt) })
+	em.Listen(func(evt scheduler.SchedulerStoppedEvent) { stopped = append(stopped, evt) })
+
+	s, _ := createSchedulerWith(scheduler.NewManager, func() event.Publisher { return em })
+	s.MustAdd("job-1", "@every 1h", func() {})
+	s.MustAdd("job-2", "@every 1h", func() {})
+	if err := s.Pause("job-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Start()
+	s.Stop()
+
+	if len(started) != 1 || started[0].JobCount != 2 || started[0].PausedCount != 1 || started[0].HasLock {
+		t.Errorf("unexpected started events: %+v", started)
+	}
+
+	if len(stopped) != 1 || len(stopped[0].Report.Paused) != 1 {
+		t.Errorf("unexpected stopped events: %+v", stopped)
+	}
+}
+
+func TestSerialLifecycleEvents(t *testing.T) {
+	em := event.NewEventManager(event.NewMemoryEventStore(false, 10))
+
+	s, _ := createSchedulerWith(scheduler.NewSerialManager, func() event.Publisher { return em })
+	s.MustAdd("job", "@every 1h", func() {})
+
+	// listeners are called synchronously, they must be able to call the scheduler
+	var jobs int
+	em.Listen(func(evt scheduler.SchedulerStartedEvent) { jobs = len(s.List()) })
+	em.Listen(func(evt scheduler.SchedulerStoppedEvent) { jobs = len(s.List()) })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Start()
+		if jobs != 1 {
+			t.Errorf("listener should see the jobs of scheduler, got %d", jobs)
+		}
+
+		s.Stop()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listeners calling the scheduler should not deadlock")
+	}
+
+	// the command goroutine exits on Stop, it's started again by the next operation
+	s.MustAdd("after-stop", "@every 1h", func() {})
+	if !hasJob(s, "after-stop") {
+		t.Error("scheduler should be usable after stopped")
+	}
+
+	s.Start()
+	s.Stop()
+}
+
+// syncHook calls the OnServerReady functions immediately, like a server which is ready already
+type syncHook struct{}
+
+func (syncHook) BeforeServerStart(f func(resolver infra.Resolver) error) {}
+func (syncHook) OnServerStop(ffs ...interface{})                         {}
+
+func (syncHook) OnServerReady(ffs ...interface{}) {
+	for _, f := range ffs {
+		f.(func())()
+	}
+}
+
+func TestSerialReentrantCalls(t *testing.T) {
+	s, _ := createSchedulerWith(scheduler.NewSerialManager, func() infra.Hook { return syncHook{} })
+
+	done := make(chan error, 1)
+	go func() {
+		// the handler is called on the command goroutine, and it calls the scheduler again
+		done <- s.AddAndRunOnServerReady("ready", "@every 1h", func() error {
+			if err := s.Pause("ready"); err != nil {
+				return err
+			}
+
+			return s.Add("added-by-ready", "@every 1h", func() {})
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the scheduler called by a command should not deadlock")
+	}
+
+	if job, _ := s.Info("ready"); !job.Paused || job.Stats.RunCount != 1 || !hasJob(s, "added-by-ready") {
+		t.Errorf("the calls from the on-ready run should be applied: %+v", job)
+	}
+
+	// the changes made by scheduler itself are sent to the command goroutine
+	s.Start()
+	defer s.Stop()
+
+	s.MustAdd("paused", "@every 1h", func() {})
+	if err := s.PauseUntil("paused", time.Now().Add(100*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RunAt("once", time.Now().Add(500*time.Millisecond), func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(2 * time.Second)
+	if job, _ := s.Info("paused"); job.Paused {
+		t.Errorf("job should be continued automatically: %+v", job)
+	}
+
+	if hasJob(s, "once") {
+		t.Error("one-shot job should be removed after executed")
+	}
+}
+
+func TestPauseAll(t *testing.T) {
+	s, cr := createScheduler()
+
+	s.MustAdd("job1", "@every 1h", func() {})
+	s.MustAdd("job2", "@every 1h", func() {})
+	if err := s.Pause("job2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.PauseAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cr.Entries()) != 0 {
+		t.Errorf("all jobs should be paused, got %d entries", len(cr.Entries()))
+	}
+
+	if err := s.ContinueAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, _ := s.Info("job1"); job.Paused {
+		t.Error("job1 should be continued")
+	}
+
+	if job, _ := s.Info("job2"); !job.Paused {
+		t.Error("job2 is paused individually, it should keep paused")
+	}
+}
+
+type memoryJobStore struct {
+	jobs  []scheduler.Job
+	saves int
+}
+
+func (s *memoryJobStore) Save(jobs []scheduler.Job) error {
+	s.jobs = jobs
+	s.saves++
+	return nil
+}
+
+func (s *memoryJobStore) Load() ([]scheduler.Job, error) {
+	return s.jobs, nil
+}
+
+func TestRestoreFrom(t *testing.T) {
+	store := &memoryJobStore{}
+
+	s1, _ := createScheduler()
+	s1.MustAdd("job", "@every 1h", func() {})
+	if err := s1.RestoreFrom(store); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s1.UpdatePlan("job", "@every 2h"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s1.Pause("job"); err != nil {
+		t.Fatal(err)
+	}
+
+	// restart with the job registered in code, and an unknown job in store
+	store.jobs = append(store.jobs, scheduler.Job{Name: "removed", Plan: "@every 1h"})
+	s2, _ := createScheduler()
+	s2.MustAdd("job", "@every 1h", func() {})
+	if err := s2.RestoreFrom(store); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, _ := s2.Info("job"); !job.Paused || job.Plan != "@every 2h" {
+		t.Errorf("job state should be restored: %+v", job)
+	}
+
+	if _, err := s2.Info("removed"); err == nil {
+		t.Error("job without handler should be skipped")
+	}
+}
+
+func TestRunsNotPersisted(t *testing.T) {
+	store := &memoryJobStore{}
+
+	s, clock := createFakeClockScheduler()
+	s.MustAdd("b", "@every 1h", func() {})
+	s.MustAdd("a", "@every 1h", func() error { return errors.New("failed") })
+	if err := s.RestoreFrom(store); err != nil {
+		t.Fatal(err)
+	}
+
+	saves := store.saves
+	records := []scheduler.ScheduleRecord{{Name: "a", ActualStart: clock.Now()}, {Name: "b", ActualStart: clock.Now()}, {Name: "b", ActualStart: clock.Now()}}
+	if err := scheduler.Replay(s, clock, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.saves != saves {
+		t.Errorf("jobs should not be saved when only stats are changed, saved %d times", store.saves-saves)
+	}
+
+	jobs := s.List()
+	if len(jobs) != 2 || jobs[0].Name != "a" || jobs[1].Name != "b" {
+		t.Fatalf("jobs should be sorted by name: %+v", jobs)
+	}
+
+	if jobs[0].Stats.FailureCount != 1 || jobs[1].Stats.RunCount != 2 {
+		t.Errorf("stats should be updated in snapshot: %+v", jobs)
+	}
+
+	if job, _ := s.Info("b"); job.Stats.RunCount != 2 || job.Running {
+		t.Errorf("stats should be updated in snapshot: %+v", job)
+	}
+}
+
+func TestMaxConcurrencySkip(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+	s.SetMaxConcurrency(1)
+	s.SetMaxConcurrencyWait(0)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	s.MustAdd("slow", "@every 1h", func() {
+		close(started)
+		<-release
+	})
+	s.MustAdd("fast", "@every 1h", func() {})
+
+	s.MustTrigger("slow")
+	<-started
+	defer close(release)
+
+	if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: "fast", ActualStart: clock.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, _ := s.Info("fast"); job.Stats.RunCount != 0 || job.LastSkipReason != scheduler.SkipReasonConcurrency {
+		t.Errorf("job should be skipped when max concurrency reached: %+v", job)
+	}
+
+	// the execution waits for a free executor before skipped
+	s.SetMaxConcurrencyWait(200 * time.Millisecond)
+	startTs := time.Now()
+	if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: "fast", ActualStart: clock.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if waited := time.Since(startTs); waited < 200*time.Millisecond {
+		t.Errorf("execution should wait for a free executor at most 200ms before skipped, waited %s", waited)
+	}
+
+	if job, _ := s.Info("fast"); job.Stats.RunCount != 0 {
+		t.Errorf("job should be skipped after waiting: %+v", job)
+	}
+}
+
+func TestStopWakesExecutionsWaitingForExecutor(t *testing.T) {
+	s, _ := createScheduler()
+	s.SetExecutorPoolSize(1)
+
+	started := make(chan struct{})
+	s.MustAdd("slow", "@every 1h", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+
+	var waitingRuns int64
+	s.MustAdd("waiting", "@every 1h", func() { atomic.AddInt64(&waitingRuns, 1) })
+
+	s.Start()
+	s.MustTrigger("slow")
+	<-started
+
+	replayed := make(chan error, 1)
+	go func() {
+		replayed <- scheduler.Replay(s, scheduler.NewFakeClock(time.Now()), []scheduler.ScheduleRecord{{Name: "waiting"}})
+	}()
+
+	// let the execution wait for the executor held by the slow one
+	time.Sleep(50 * time.Millisecond)
+	s.Stop()
+
+	select {
+	case err := <-replayed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("execution waiting for an executor should return when scheduler stopped")
+	}
+
+	if runs := atomic.LoadInt64(&waitingRuns); runs != 0 {
+		t.Errorf("execution waiting for an executor should be abandoned after stopped, got %d runs", runs)
+	}
+}
+
+func TestRunAfter(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	ingestErr := fmt.Errorf("ingest failed")
+	s.MustAdd("ingest", "@every 1h", func() error { return ingestErr })
+	s.MustAdd("aggregate", "@every 1h", func() {}, scheduler.WithRunAfter("ingest"))
+
+	run := func(name string) {
+		clock.Advance(time.Minute)
+		if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: name, ActualStart: clock.Now()}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expect := func(runs int64, reason string) {
+		if job, _ := s.Info("aggregate"); job.Stats.RunCount != runs || job.LastSkipReason != reason {
+			t.Errorf("expect %d runs and skip reason [%s], got %d runs and [%s]", runs, reason, job.Stats.RunCount, job.LastSkipReason)
+		}
+	}
+
+	run("aggregate")
+	expect(0, scheduler.SkipReasonDependencyPending)
+
+	run("ingest")
+	run("aggregate")
+	expect(0, scheduler.SkipReasonDependencyFailed)
+
+	ingestErr = nil
+	run("ingest")
+	run("aggregate")
+	expect(1, scheduler.SkipReasonDependencyFailed)
+
+	// ingest has not run again since the last cycle of aggregate
+	run("aggregate")
+	expect(1, scheduler.SkipReasonDependencyPending)
+
+	if err := s.Add("typo", "@every 1h", func() {}, scheduler.WithRunAfter("ingset")); err == nil {
+		t.Error("prerequisite not registered should be rejected")
+	}
+
+	if err := s.Add("self", "@every 1h", func() {}, scheduler.WithRunAfter("self")); err == nil {
+		t.Error("job should not run after itself")
+	}
+
+	if _, err := s.Reconcile([]scheduler.JobConfig{
+		{Name: "report", Plan: "@every 1h", Handler: func() {}, Options: []scheduler.JobOption{scheduler.WithRunAfter("ingest")}},
+	}); err == nil {
+		t.Error("prerequisite removed by reconcile should be rejected")
+	}
+
+	if _, err := s.Reconcile([]scheduler.JobConfig{
+		{Name: "report", Plan: "@every 1h", Handler: func() {}, Options: []scheduler.JobOption{scheduler.WithRunAfter("export")}},
+		{Name: "export", Plan: "@every 1h", Handler: func() {}},
+	}); err != nil {
+		t.Errorf("prerequisite added by the same reconcile should be accepted: %v", err)
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	s.MustAdd("job", "@every 1m", func() {})
+	if err := s.CheckHealth(); err == nil {
+		t.Error("scheduler is not started, it should be unhealthy")
+	}
+
+	s.Start()
+	defer s.Stop()
+
+	if err := s.CheckHealth(); err != nil {
+		t.Errorf("scheduler should be healthy: %v", err)
+	}
+
+	clock.Advance(3 * time.Minute)
+	if err := s.CheckHealth(); err == nil {
+		t.Error("job is not processed for 3 minutes, it should be unhealthy")
+	}
+}
+
+func hasJob(s scheduler.Scheduler, name string) bool {
+	_, err := s.Info(name)
+	return err == nil
+}
+
+func TestMiddlewares(t *testing.T) {
+	s, _ := createScheduler()
+
+	lockManager := &switchLockManager{}
+	s.LockManagerBuilder(func(name string) scheduler.LockManager { return lockManager })
+
+	events := &eventLog{}
+	s.Use(
+		func(next func(), job scheduler.Job) func() {
+			return func() {
+				events.add("outer:" + job.Name)
+				next()
+			}
+		},
+		func(next func(), job scheduler.Job) func() {
+			return func() {
+				events.add("inner:" + job.Name)
+				switch job.Name {
+				case "disabled":
+					return
+				case "broken":
+					panic("middleware panicked")
+				}
+
+				next()
+			}
+		},
+	)
+
+	for _, name := range []string{"enabled", "disabled", "broken"} {
+		name := name
+		s.MustAdd(name, "@every 1h", func() { events.add("run:" + name) })
+	}
+
+	s.Start()
+	for _, name := range []string{"enabled", "disabled", "broken"} {
+		s.MustTrigger(name)
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// middlewares only run on the node which holds the distributed lock
+	lockManager.fail(scheduler.ErrLockFailed)
+	s.MustTrigger("enabled")
+	time.Sleep(50 * time.Millisecond)
+	s.Stop()
+
+	if got := fmt.Sprint(events.list()); got != "[outer:enabled inner:enabled run:enabled outer:disabled inner:disabled outer:broken inner:broken]" {
+		t.Errorf("middlewares should wrap the jobs in registration order, got %s", got)
+	}
+
+	if job, _ := s.Info("enabled"); job.Stats.RunCount != 1 || job.LastSkipReason != scheduler.SkipReasonNotLeader {
+		t.Errorf("job should run once on the leader, got %+v, %s", job.Stats, job.LastSkipReason)
+	}
+
+	if job, _ := s.Info("disabled"); job.Stats.RunCount != 0 || job.LastSkipReason != scheduler.SkipReasonMiddleware {
+		t.Errorf("short-circuited execution should be skipped, got %+v, %s", job.Stats, job.LastSkipReason)
+	}
+
+	if job, _ := s.Info("broken"); job.Stats.RunCount != 1 || job.Stats.FailureCount != 1 {
+		t.Errorf("panicking middleware should fail the execution, got %+v", job.Stats)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	s, _ := createScheduler()
+	s.SetRecorder(scheduler.NewMemoryRecorder(10))
+	s.MustAdd("job", "@every 1h", func() {})
+
+	s.Start()
+	defer s.Stop()
+
+	handler := scheduler.AdminHandler(s)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/jobs/job/trigger", ""); w.Code != http.StatusAccepted {
+		t.Errorf("job should be triggered, got %d", w.Code)
+	}
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		// the run is recorded after the stats are updated
+		if history, _ := s.History("job", 0); len(history) == 1 {
+			break
+		}
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{http.MethodGet, "/jobs", "", http.StatusOK},
+		{http.MethodGet, "/jobs/job", "", http.StatusOK},
+		{http.MethodGet, "/jobs/job/stats", "", http.StatusOK},
+		{http.MethodGet, "/jobs/job/history?limit=x", "", http.StatusBadRequest},
+		{http.MethodGet, "/jobs/job/history?limit=-1", "", http.StatusBadRequest},
+		{http.MethodPost, "/jobs/job/pause", "", http.StatusOK},
+		{http.MethodPost, "/jobs/job/continue", "", http.StatusOK},
+		{http.MethodPut, "/jobs/job/plan", `{"plan": "@every 1m"}`, http.StatusOK},
+		{http.MethodPut, "/jobs/job/plan", `not json`, http.StatusBadRequest},
+		{http.MethodPut, "/jobs/job/plan", `{"plan": ""}`, http.StatusBadRequest},
+		{http.MethodGet, "/metrics", "", http.StatusOK},
+		{http.MethodGet, "/jobs/missing", "", http.StatusNotFound},
+		{http.MethodGet, "/jobs/missing/stats", "", http.StatusNotFound},
+		{http.MethodGet, "/jobs/missing/history", "", http.StatusNotFound},
+		{http.MethodDelete, "/jobs/missing", "", http.StatusNotFound},
+		{http.MethodPost, "/jobs/missing/pause", "", http.StatusNotFound},
+		{http.MethodPost, "/jobs/missing/continue", "", http.StatusNotFound},
+		{http.MethodPost, "/jobs/missing/trigger", "", http.StatusNotFound},
+		{http.MethodPut, "/jobs/missing/plan", `{"plan": "@every 1m"}`, http.StatusNotFound},
+	} {
+		if w := serve(tc.method, tc.path, tc.body); w.Code != tc.code {
+			t.Errorf("%s %s should respond %d, got %d: %s", tc.method, tc.path, tc.code, w.Code, w.Body.String())
+		}
+	}
+
+	if job, _ := s.Info("job"); job.Paused || job.Plan != "@every 1m" || job.Stats.RunCount != 1 {
+		t.Errorf("job should be changed by admin requests, got %+v", job)
+	}
+
+	var history []scheduler.ScheduleRecord
+	if w := serve(http.MethodGet, "/jobs/job/history?limit=1", ""); w.Code != http.StatusOK {
+		t.Errorf("history should be served, got %d", w.Code)
+	} else if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil || len(history) != 1 || history[0].Name != "job" {
+		t.Errorf("history should contain the triggered run, got %s", w.Body.String())
+	}
+
+	if w := serve(http.MethodGet, "/metrics", ""); w.Header().Get("Content-Type") != scheduler.MetricsContentType || !strings.Contains(w.Body.String(), `glacier_scheduler_job_runs_total{job="job"} 1`) {
+		t.Errorf("metrics should be served in OpenMetrics format, got %s", w.Body.String())
+	}
+
+	if w := serve(http.MethodDelete, "/jobs/job", ""); w.Code != http.StatusOK || hasJob(s, "job") {
+		t.Errorf("job should be removed, got %d", w.Code)
+	}
+
+	// the history is not available when the recorder can not query records
+	s.SetRecorder(&memoryRecorder{})
+	s.MustAdd("job", "@every 1h", func() {})
+	if w := serve(http.MethodGet, "/jobs/job/history", ""); w.Code != http.StatusNotImplemented {
+		t.Errorf("history should not be implemented without a history recorder, got %d", w.Code)
+	}
+}
+
+func TestMemoryRecorder(t *testing.T) {
+	recorder := scheduler.NewMemoryRecorder(2)
+	for i := 0; i < 3; i++ {
+		_ = recorder.Record(scheduler.ScheduleRecord{Name: "job", Error: fmt.Sprint(i)})
+	}
+
+	history, _ := recorder.History("job", 0)
+	if len(history) != 2 || history[0].Error != "2" || history[1].Error != "1" {
+		t.Errorf("only the latest records should be kept, newest first, got %+v", history)
+	}
+
+	if history, _ := recorder.History("job", 1); len(history) != 1 || history[0].Error != "2" {
+		t.Errorf("history should be limited, got %+v", history)
+	}
+}
+
+func TestFailureBackoffWithoutMax(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+	s.MustAdd("failing", "@every 1h", func() error { return errors.New("failed") }, scheduler.WithFailureBackoff(time.Second, 0))
+
+	// the backoff is capped, so it never overflows after many failures
+	records := make([]scheduler.ScheduleRecord, 0, 100)
+	for i := 0; i < 100; i++ {
+		records = append(records, scheduler.ScheduleRecord{Name: "failing", ActualStart: clock.Now().Add(time.Duration(i) * (scheduler.DefaultFailureBackoffMax + time.Second))})
+	}
+
+	if err := scheduler.Replay(s, clock, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, _ := s.Info("failing"); job.Stats.ConsecutiveFailures != 100 {
+		t.Fatalf("all executions should run after the backoff, got %+v", job.Stats)
+	}
+
+	clock.Advance(time.Hour)
+	if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: "failing", ActualStart: clock.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, _ := s.Info("failing"); job.Stats.RunCount != 100 || job.LastSkipReason != scheduler.SkipReasonBackoff {
+		t.Errorf("execution should be skipped in the backoff, got %+v, %s", job.Stats, job.LastSkipReason)
+	}
+}
+
+func TestMutexGroup(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	holding, release, released := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	s.MustAdd("holder", "@every 1h", func() {
+		defer close(released)
+
+		close(holding)
+		<-release
+	}, scheduler.WithMutexGroup("table"))
+	s.MustAdd("peer", "@every 1h", func() {}, scheduler.WithMutexGroup("table"))
+	s.MustAdd("other", "@every 1h", func() {}, scheduler.WithMutexGroup("another-table"))
+
+	replay := func(name string) scheduler.Job {
+		if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: name, ActualStart: clock.Now()}}); err != nil {
+			t.Fatal(err)
+		}
+
+		job, _ := s.Info(name)
+		return job
+	}
+
+	s.MustTrigger("holder")
+	<-holding
+
+	if job := replay("peer"); job.Stats.RunCount != 0 || job.LastSkipReason != scheduler.SkipReasonMutexGroup {
+		t.Errorf("job should be skipped while another job in its group is running, got %+v, %s", job.Stats, job.LastSkipReason)
+	}
+
+	if job := replay("other"); job.Stats.RunCount != 1 {
+		t.Errorf("job in another group should run, got %+v", job.Stats)
+	}
+
+	close(release)
+	<-released
+
+	// the group lock is released right after the handler returns
+	var job scheduler.Job
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if job = replay("peer"); job.Stats.RunCount == 1 {
+			break
+		}
+	}
+
+	if job.Stats.RunCount != 1 {
+		t.Errorf("job should run after the group is released, got %+v", job.Stats)
+	}
+}
+
+func TestRegisterStruct(t *testing.T) {
+	s, _ := createSchedulerWith(scheduler.NewManager, func() infra.Hook { return syncHook{} })
+
+	var readyRuns int32
+	jobs := struct {
+		Cleanup  func()       `cron:"@every 5s" name:"cleanup" exclusive:"true"`
+		Report   func() error `cron:"@every 1h" onready:"true"`
+		Untagged func()
+	}{
+		Cleanup:  func() {},
+		Report:   func() error { atomic.AddInt32(&readyRuns, 1); return nil },
+		Untagged: func() {},
+	}
+
+	if err := s.RegisterStruct(&jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, err := s.Info("cleanup"); err != nil || job.Plan != "@every 5s" {
+		t.Errorf("job should be named by the name tag, got %+v, %v", job, err)
+	}
+
+	if !hasJob(s, "Report") || atomic.LoadInt32(&readyRuns) != 1 {
+		t.Errorf("job should be named by the field and run on server ready, runs %d", atomic.LoadInt32(&readyRuns))
+	}
+
+	if hasJob(s, "Untagged") {
+		t.Error("fields without cron tag should be skipped")
+	}
+
+	for name, v := range map[string]interface{}{
+		"not struct": "cleanup",
+		"empty plan": &struct {
+			Job func() `cron:""`
+		}{Job: func() {}},
+		"unexported": &struct {
+			job func() `cron:"@every 1h"`
+		}{job: func() {}},
+		"not function": &struct {
+			Job string `cron:"@every 1h"`
+		}{Job: "cleanup"},
+		"nil function": &struct {
+			Job func() `cron:"@every 1h"`
+		}{},
+		"invalid flag": &struct {
+			Job func() `cron:"@every 1h" name:"invalid-flag" exclusive:"yes please"`
+		}{Job: func() {}},
+		"invalid plan": &struct {
+			Job func() `cron:"every hour" name:"invalid-plan"`
+		}{Job: func() {}},
+	} {
+		if err := s.RegisterStruct(v); err == nil {
+			t.Errorf("%s should be rejected", name)
+		}
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	s, _ := createScheduler()
+
+	s.Namespace("plugin-a").MustAdd("cleanup", "@every 1h", func() {})
+	if err := s.Namespace("plugin-b").Add("cleanup", "@every 1h", func() {}); err != nil {
+		t.Fatalf("jobs with the same name in different namespaces should not collide: %v", err)
+	}
+
+	if err := s.Namespace("plugin-a").Add("cleanup", "@every 1h", func() {}); err == nil {
+		t.Error("duplicated job in the same namespace should be rejected")
+	}
+
+	if err := s.Namespace("plugin-a").Namespace("sub").RegisterStruct(&struct {
+		Report func() `cron:"@every 1h" name:"report"`
+	}{Report: func() {}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string][2]string{
+		"plugin-a.cleanup":    {"plugin-a", "cleanup"},
+		"plugin-b.cleanup":    {"plugin-b", "cleanup"},
+		"plugin-a.sub.report": {"plugin-a.sub", "report"},
+	} {
+		job, err := s.Info(name)
+		if err != nil {
+			t.Errorf("job %s should be added: %v", name, err)
+			continue
+		}
+
+		if job.Namespace != expected[0] || job.ShortName != expected[1] {
+			t.Errorf("job %s should have namespace %s and short name %s, got %s, %s", name, expected[0], expected[1], job.Namespace, job.ShortName)
+		}
+	}
+}
+
+func TestActiveHours(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+
+	// 2024-01-01 is a Monday
+	loc := time.FixedZone("UTC+8", 8*3600)
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	s.MustAdd("business", "@every 1h", func() {}, scheduler.WithActiveHours(weekdays, "09:00", "17:00", loc))
+	s.MustAdd("overnight", "@every 1h", func() {}, scheduler.WithActiveHours(nil, "22:00", "02:00", loc))
+
+	for _, tc := range []struct {
+		name string
+		at   time.Time
+		ran  bool
+	}{
+		{"business", time.Date(2024, 1, 1, 10, 0, 0, 0, loc), true},
+		{"business", time.Date(2024, 1, 1, 8, 59, 0, 0, loc), false},
+		{"business", time.Date(2024, 1, 1, 17, 0, 0, 0, loc), false},
+		{"business", time.Date(2024, 1, 6, 10, 0, 0, 0, loc), false},
+		// the window is in the location of option, it's 10:00 in UTC+8
+		{"business", time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC), true},
+		{"overnight", time.Date(2024, 1, 1, 23, 0, 0, 0, loc), true},
+		{"overnight", time.Date(2024, 1, 2, 1, 0, 0, 0, loc), true},
+		{"overnight", time.Date(2024, 1, 2, 3, 0, 0, 0, loc), false},
+	} {
+		before, _ := s.Info(tc.name)
+		if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: tc.name, ActualStart: tc.at}}); err != nil {
+			t.Fatal(err)
+		}
+
+		after, _ := s.Info(tc.name)
+		if ran := after.Stats.RunCount > before.Stats.RunCount; ran != tc.ran {
+			t.Errorf("job %s at %s should run: %v, got %v", tc.name, tc.at, tc.ran, ran)
+		}
+
+		if !tc.ran && after.LastSkipReason != scheduler.SkipReasonInactive {
+			t.Errorf("job %s at %s should be skipped as inactive, got %s", tc.name, tc.at, after.LastSkipReason)
+		}
+	}
+
+	if err := s.Add("invalid", "@every 1h", func() {}, scheduler.WithActiveHours(nil, "9am", "17:00", nil)); err == nil {
+		t.Error("invalid active hours should be rejected")
+	}
+}
+
+func TestTagConcurrency(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+	s.SetTagConcurrency("tenant:a", 2)
+	s.SetTagConcurrency("shared", 1)
+
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	for _, name := range []string{"a1", "a2"} {
+		s.MustAdd(name, "@every 1h", func() {
+			started <- struct{}{}
+			<-release
+		}, scheduler.WithTags("tenant:a"))
+	}
+
+	s.MustAdd("a3", "@every 1h", func() {}, scheduler.WithTags("tenant:a", "shared"))
+	s.MustAdd("b", "@every 1h", func() {}, scheduler.WithTags("tenant:b", "shared"))
+
+	replay := func(name string) scheduler.Job {
+		if err := scheduler.Replay(s, clock, []scheduler.ScheduleRecord{{Name: name, ActualStart: clock.Now()}}); err != nil {
+			t.Fatal(err)
+		}
+
+		job, _ := s.Info(name)
+		return job
+	}
+
+	s.MustTrigger("a1")
+	s.MustTrigger("a2")
+	<-started
+	<-started
+
+	if job := replay("a3"); job.Stats.RunCount != 0 || job.LastSkipReason != scheduler.SkipReasonTagConcurrency {
+		t.Errorf("job should be skipped when the limit of its tag is reached, got %+v, %s", job.Stats, job.LastSkipReason)
+	}
+
+	// the semaphore of "shared" acquired by the skipped job is released
+	if job := replay("b"); job.Stats.RunCount != 1 {
+		t.Errorf("job of another tenant should run, got %+v, %s", job.Stats, job.LastSkipReason)
+	}
+
+	if jobs := s.ListByTag("tenant:a"); len(jobs) != 3 {
+		t.Errorf("jobs should be listed by tag, got %d", len(jobs))
+	}
+
+	close(release)
+
+	var job scheduler.Job
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if job = replay("a3"); job.Stats.RunCount == 1 {
+			break
+		}
+	}
+
+	if job.Stats.RunCount != 1 {
+		t.Errorf("job should run after the running jobs of its tag finished, got %+v", job.Stats)
+	}
+}
+
+func TestPauseUntil(t *testing.T) {
+	s, cr := createScheduler()
+	s.MustAdd("snoozed", "@every 1h", func() {})
+	s.MustAdd("extended", "@every 1h", func() {})
+	s.MustAdd("paused", "@every 1h", func() {})
+
+	if err := s.PauseUntil("snoozed", time.Now().Add(-time.Second)); err == nil {
+		t.Error("job should not be paused until a time in the past")
+	}
+
+	until := time.Now().Add(200 * time.Millisecond)
+	for _, name := range []string{"snoozed", "extended", "paused"} {
+		if err := s.PauseUntil(name, until); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if job, _ := s.Info("snoozed"); !job.Paused || !job.PausedUntil.Equal(until) || len(cr.Entries()) != 0 {
+		t.Errorf("job should be paused until %s, got %+v", until, job)
+	}
+
+	// a later pause replaces the automatic resume
+	if err := s.PauseUntil("extended", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Pause("paused"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	if job, _ := s.Info("snoozed"); job.Paused || !job.PausedUntil.IsZero() || len(cr.Entries()) != 1 {
+		t.Errorf("job should be continued automatically, got %+v", job)
+	}
+
+	for _, name := range []string{"extended", "paused"} {
+		if job, _ := s.Info(name); !job.Paused {
+			t.Errorf("job %s should be kept paused after the replaced resume time", name)
+		}
+	}
+}
+
+func TestCronAdapter(t *testing.T) {
+	s, clock := createFakeClockScheduler()
+	adapter := scheduler.NewCronAdapter(s, "legacy")
+
+	var runs int32
+	funcID, err := adapter.AddFunc("@every 10s", func() { atomic.AddInt32(&runs, 1) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobID, err := adapter.AddJob("@every 1m", cron.FuncJob(func() { panic("legacy job panicked") }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := adapter.AddFunc("every 10 seconds", func() {}); err == nil {
+		t.Error("invalid spec should be rejected")
+	}
+
+	funcName, _ := adapter.Name(funcID)
+	jobName, _ := adapter.Name(jobID)
+	if job, err := s.InfoByID(funcID); err != nil || job.Name != funcName || funcName != "legacy-1" || job.Plan != "@every 10s" {
+		t.Errorf("func should be added as a job of scheduler, got %+v, %v", job, err)
+	}
+
+	records := []scheduler.ScheduleRecord{{Name: funcName, ActualStart: clock.Now()}, {Name: jobName, ActualStart: clock.Now()}}
+	if err := scheduler.Replay(s, clock, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, _ := s.Info(jobName); atomic.LoadInt32(&runs) != 1 || job.Stats.FailureCount != 1 {
+		t.Errorf("jobs added by adapter should run with panic recovery, runs %d, got %+v", atomic.LoadInt32(&runs), job.Stats)
+	}
+
+	adapter.Remove(funcID)
+	adapter.Remove(funcID)
+	if hasJob(s, funcName) || !hasJob(s, jobName) {
+		t.Error("only the job of the entry should be removed")
+	}
+
+	if _, ok := adapter.Name(funcID); ok {
+		t.Error("removed entry should be forgotten")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	payloads := make(chan scheduler.WebhookPayload, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+
+		var payload scheduler.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || r.Method != http.MethodPost {
+			t.Errorf("webhook should post a JSON payload: %v", err)
+		}
+
+		payload.Status = r.URL.Path + ":" + payload.Status
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	s, clock := createFakeClockScheduler()
+	s.MustAdd("succeeded", "@every 1h", func() {}, scheduler.WithWebhook(server.URL+"/success", server.URL+"/failure"))
+	s.MustAdd("failed", "@every 1h", func() error { return errors.New("failed") }, scheduler.WithWebhook(server.URL+"/success", server.URL+"/failure"))
+	s.MustAdd("slow", "@every 1h", func() {}, scheduler.WithWebhook(server.URL+"/slow", ""))
+	s.MustAdd("silent", "@every 1h", func() error { return errors.New("failed") }, scheduler.WithWebhook(server.URL+"/success", ""))
+
+	startTs := time.Now()
+	records := make([]scheduler.ScheduleRecord, 0)
+	for _, name := range []string{"succeeded", "failed", "slow", "silent"} {
+		records = append(records, scheduler.ScheduleRecord{Name: name, ActualStart: clock.Now()})
+	}
+
+	if err := scheduler.Replay(s, clock, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(startTs); elapsed > 300*time.Millisecond {
+		t.Errorf("webhook should be delivered asynchronously, the executions took %s", elapsed)
+	}
+
+	received := make(map[string]scheduler.WebhookPayload)
+	for i := 0; i < 3; i++ {
+		select {
+		case payload := <-payloads:
+			received[payload.Job] = payload
+		case <-time.After(2 * time.Second):
+			t.Fatalf("webhook should be delivered, got %+v", received)
+		}
+	}
+
+	if payload := received["succeeded"]; payload.Status != "/success:success" || payload.Error != "" {
+		t.Errorf("success should be posted to the success webhook, got %+v", payload)
+	}
+
+	if payload := received["failed"]; payload.Status != "/failure:failure" || payload.Error != "failed" {
+		t.Errorf("failure should be posted to the failure webhook with error, got %+v", payload)
+	}
+
+	if _, ok := received["silent"]; ok {
+		t.Error("failure should not be posted without failure webhook")
+	}
+}
@@ -0,0 +1,20 @@
+package scheduler
+
+// SetDefaults set the default options of jobs added after it, they are applied before the options
+// passed to Add, so the options of job override the defaults. Options which accumulate values, such
+// as WithTags, add the values of job to the defaults.
+func (c *schedulerImpl) SetDefaults(options ...JobOption) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.defaults = append([]JobOption{}, options...)
+}
+
+// jobOptions build the options of job with the defaults, the caller must hold the lock
+func (c *schedulerImpl) jobOptions(options []JobOption) jobOptions {
+	if len(c.defaults) == 0 {
+		return newJobOptions(options...)
+	}
+
+	return newJobOptions(append(append([]JobOption{}, c.defaults...), options...)...)
+}
@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// cycleTracker tracks the start of current cycle of a job for checking prerequisites, see WithRunAfter
+type cycleTracker struct {
+	lock  sync.Mutex
+	start time.Time
+}
+
+// next start a new cycle at now, and return the start of the previous one
+func (t *cycleTracker) next(now time.Time) time.Time {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	prev := t.start
+	t.start = now
+	return prev
+}
+
+// checkRunAfter check the prerequisites of job set by WithRunAfter, exists reports whether a user job
+// with the name is (or will be) registered
+func checkRunAfter(job *Job, exists func(name string) bool) error {
+	for _, name := range job.options.runAfter {
+		if name == job.Name {
+			return fmt.Errorf("[glacier] job [%s] can not run after itself", job.Name)
+		}
+
+		if !exists(name) {
+			return fmt.Errorf("[glacier] prerequisite [%s] of job [%s] does not exist", name, job.Name)
+		}
+	}
+
+	return nil
+}
+
+// unmetDependency check the prerequisites of job set by WithRunAfter, and start a new cycle of the job.
+// It returns the skip reason if any prerequisite has not completed successfully in the current cycle,
+// which starts at the previous scheduled time of the job (or when the job becomes active).
+// The prerequisites are read from the snapshot, so the check never blocks other jobs
+func (c *schedulerImpl) unmetDependency(job *Job) string {
+	if len(job.options.runAfter) == 0 {
+		return ""
+	}
+
+	since := job.cycle.next(c.clock.Now())
+	snap := c.snapshot.Load()
+	if since.IsZero() {
+		since = snap.jobs[job.Name].addedAt
+
+		c.lock.RLock()
+		if c.startedAt.After(since) {
+			since = c.startedAt
+		}
+		c.lock.RUnlock()
+	}
+
+	for _, name := range job.options.runAfter {
+		dep, ok := snap.jobs[name]
+		if !ok {
+			return SkipReasonDependencyPending
+		}
+
+		st := dep.loadState()
+		if st.finishedAt.IsZero() || st.finishedAt.Before(since) {
+			return SkipReasonDependencyPending
+		}
+
+		if st.stats.LastError != "" {
+			return SkipReasonDependencyFailed
+		}
+	}
+
+	return ""
+}
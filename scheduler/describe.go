@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	cron "github.com/robfig/cron/v3"
+)
+
+// starBit is the bit set by cron parser when a field is "*" or "?"
+const starBit = 1 << 63
+
+// describeField is the name and value range of a field of cron spec
+type describeField struct {
+	name     string
+	min, max uint
+	names    []string
+}
+
+var (
+	secondField = describeField{name: "second", min: 0, max: 59}
+	minuteField = describeField{name: "minute", min: 0, max: 59}
+	hourField   = describeField{name: "hour", min: 0, max: 23}
+	domField    = describeField{name: "day-of-month", min: 1, max: 31}
+	monthField  = describeField{name: "month", min: 1, max: 12, names: []string{"", "Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}}
+	dowField    = describeField{name: "day-of-week", min: 0, max: 6, names: []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}}
+)
+
+// ValidatePlan check whether the plan can be parsed by the parser of scheduler, which is the same one used by
+// Add and Job.Next
+func (c *schedulerImpl) ValidatePlan(plan string) error {
+	if _, err := c.parser.Parse(plan); err != nil {
+		return errors.Wrapf(err, "[glacier] invalid plan [%s]", plan)
+	}
+
+	return nil
+}
+
+// Describe return a human-readable summary of the plan, e.g. "every 1h0m0s" or "at 09:30:00, day-of-week Mon-Fri",
+// an error is returned if the plan is invalid
+func (c *schedulerImpl) Describe(plan string) (string, error) {
+	sc, err := c.parser.Parse(plan)
+	if err != nil {
+		return "", errors.Wrapf(err, "[glacier] invalid plan [%s]", plan)
+	}
+
+	switch s := sc.(type) {
+	case cron.ConstantDelaySchedule:
+		return fmt.Sprintf("every %s", s.Delay), nil
+	case *cron.SpecSchedule:
+		return describeSpec(s), nil
+	default:
+		return plan, nil
+	}
+}
+
+func describeSpec(spec *cron.SpecSchedule) string {
+	var parts []string
+
+	second, secondOK := singleValue(spec.Second, secondField)
+	minute, minuteOK := singleValue(spec.Minute, minuteField)
+	hour, hourOK := singleValue(spec.Hour, hourField)
+	if secondOK && minuteOK && hourOK {
+		parts = append(parts, fmt.Sprintf("at %02d:%02d:%02d", hour, minute, second))
+	} else {
+		for _, f := range []struct {
+			bits  uint64
+			field describeField
+		}{{spec.Second, secondField}, {spec.Minute, minuteField}, {spec.Hour, hourField}} {
+			parts = append(parts, describeBits(f.bits, f.field))
+		}
+	}
+
+	for _, f := range []struct {
+		bits  uint64
+		field describeField
+	}{{spec.Dom, domField}, {spec.Month, monthField}, {spec.Dow, dowField}} {
+		if f.bits&starBit == 0 {
+			parts = append(parts, describeBits(f.bits, f.field))
+		}
+	}
+
+	if len(parts) == 1 && secondOK && minuteOK && hourOK {
+		parts[0] += " every day"
+	}
+
+	if spec.Location != nil && spec.Location != time.Local {
+		parts = append(parts, "in "+spec.Location.String())
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// singleValue return the value of the field if only one value is set
+func singleValue(bits uint64, field describeField) (uint, bool) {
+	if bits&starBit != 0 {
+		return 0, false
+	}
+
+	var value uint
+	var count int
+	for i := field.min; i <= field.max; i++ {
+		if bits&(1<<i) != 0 {
+			value = i
+			count++
+		}
+	}
+
+	return value, count == 1
+}
+
+// describeBits describe the values of field as ranges, e.g. "minute 0-5,30"
+func describeBits(bits uint64, field describeField) string {
+	if bits&starBit != 0 {
+		return "every " + field.name
+	}
+
+	var ranges []string
+	for i := field.min; i <= field.max; i++ {
+		if bits&(1<<i) == 0 {
+			continue
+		}
+
+		j := i
+		for j+1 <= field.max && bits&(1<<(j+1)) != 0 {
+			j++
+		}
+
+		if i == j {
+			ranges = append(ranges, field.format(i))
+		} else {
+			ranges = append(ranges, field.format(i)+"-"+field.format(j))
+		}
+
+		i = j
+	}
+
+	return field.name + " " + strings.Join(ranges, ",")
+}
+
+func (field describeField) format(value uint) string {
+	if int(value) < len(field.names) && field.names[value] != "" {
+		return field.names[value]
+	}
+
+	return strconv.Itoa(int(value))
+}
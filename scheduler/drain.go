@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// DrainPolicy decides what to do when the running executions are not finished in drain timeout on Stop
+type DrainPolicy int
+
+const (
+	// WaitOnTimeout keep waiting for the running executions after timeout, a job blocking forever
+	// will block the shutdown until the process is terminated by the graceful shutdown deadline
+	WaitOnTimeout DrainPolicy = iota
+	// DetachOnTimeout stop waiting after timeout, the running executions are logged and left running in
+	// background, they are killed when the process exits
+	DetachOnTimeout
+)
+
+// SetDrain make Stop wait for the running executions at most timeout, and then act as policy.
+// timeout <= 0 means Stop waits until all running executions are finished, which is the default behavior
+func (c *schedulerImpl) SetDrain(timeout time.Duration, policy DrainPolicy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.drainTimeout = timeout
+	c.drainPolicy = policy
+}
+
+// drain wait for the executions in progress after cron stopped as SetDrain configured,
+// done is closed when they are all finished
+func (c *schedulerImpl) drain(done context.Context) {
+	c.lock.RLock()
+	timeout, policy := c.drainTimeout, c.drainPolicy
+	c.lock.RUnlock()
+
+	c.drainWithin(done, timeout, policy)
+}
+
+// drainWithin wait for the executions in progress at most timeout, and then act as policy,
+// timeout <= 0 means waiting without limit
+func (c *schedulerImpl) drainWithin(done context.Context, timeout time.Duration, policy DrainPolicy) {
+	if timeout <= 0 {
+		<-done.Done()
+		return
+	}
+
+	if infra.DEBUG {
+		log.Debugf("[glacier] scheduler is waiting for running jobs, timeout %s", timeout)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done.Done():
+		return
+	case <-timer.C:
+	}
+
+	running := c.shutdownReport().Running
+	if policy == DetachOnTimeout {
+		log.WithFields(infra.Fields{"jobs": running, "timeout": timeout}).Errorf("[glacier] running jobs are not finished in %s, detached: %v", timeout, running)
+		return
+	}
+
+	if infra.WARN {
+		log.WithFields(infra.Fields{"jobs": running, "timeout": timeout}).Warningf("[glacier] running jobs are not finished in %s, keep waiting: %v", timeout, running)
+	}
+
+	<-done.Done()
+}
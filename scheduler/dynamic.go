@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+	"github.com/robfig/cron/v3"
+)
+
+// DynamicPlan is the plan of jobs added by AddDynamic
+const DynamicPlan = "@dynamic"
+
+const (
+	dynamicRetryBase = time.Second
+	dynamicRetryMax  = 5 * time.Minute
+
+	// dynamicFiringCheck is the initial interval to check whether the fired execution is reported,
+	// it's doubled on every check up to dynamicFiringCheckMax
+	dynamicFiringCheck    = 100 * time.Millisecond
+	dynamicFiringCheckMax = 5 * time.Second
+)
+
+var errDynamicPlan = errors.New("[glacier] the plan of dynamic job can not be parsed")
+
+// dynamicSchedule is a cron.Schedule whose next time is computed by a user function.
+//
+// When the function fails, a probe time is returned to retry it with backoff, the executions at
+// probe times only make cron compute the next time again, the handler is not called. After a real
+// execution is fired, the next time is computed only after the cron job reports it (probes are
+// returned until then), so last is always set by the cron job itself, never guessed by Next
+type dynamicSchedule struct {
+	lock     sync.Mutex
+	name     string
+	next     func(last time.Time) (time.Time, error)
+	last     time.Time
+	pending  time.Time
+	failures int
+	probes   map[time.Time]bool
+	// firing is true when a real execution is fired, and it has not been reported by the cron job
+	firing bool
+	checks int
+}
+
+func (d *dynamicSchedule) Next(t time.Time) time.Time {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	// pending is reset whenever the job is (re)scheduled, so a reached pending time which is not
+	// a probe means cron has just fired a real execution
+	if !d.pending.IsZero() && !d.pending.After(t) && !d.probes[d.pending] {
+		d.firing = true
+	}
+
+	if d.firing {
+		delay := dynamicFiringCheck << minInt(d.checks, 6)
+		if delay > dynamicFiringCheckMax {
+			delay = dynamicFiringCheckMax
+		}
+
+		d.checks++
+		return d.probe(t, delay)
+	}
+
+	at, err := d.next(d.last)
+	if err != nil {
+		d.failures++
+		delay := dynamicRetryBase << minInt(d.failures-1, 16)
+		if delay > dynamicRetryMax {
+			delay = dynamicRetryMax
+		}
+
+		log.WithFields(infra.Fields{"job": d.name, "error": err, "retry_after": delay}).Errorf("[glacier] dynamic job [%s] can not get next time, retry after %s", d.name, delay)
+
+		return d.probe(t, delay)
+	}
+
+	d.failures = 0
+
+	// zero time means the job will never be executed again
+	if at.IsZero() {
+		d.pending = time.Time{}
+		return at
+	}
+
+	// avoid busy loop when next returns a time in the past
+	if !at.After(t) {
+		at = t.Add(time.Second)
+	}
+
+	d.pending = at
+	return at
+}
+
+// probe return a probe time after delay, the caller must hold the lock
+func (d *dynamicSchedule) probe(t time.Time, delay time.Duration) time.Time {
+	d.pending = t.Add(delay)
+	d.probes[d.pending] = true
+	return d.pending
+}
+
+// consumeProbe check whether the scheduled time is a probe, the probe is removed after checked
+func (d *dynamicSchedule) consumeProbe(scheduled time.Time) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if !d.probes[scheduled] {
+		return false
+	}
+
+	delete(d.probes, scheduled)
+	return true
+}
+
+// fired is called by the cron job after a real execution at scheduled is finished or skipped
+func (d *dynamicSchedule) fired(scheduled time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.last = scheduled
+	d.firing = false
+	d.checks = 0
+}
+
+// reset clear the state of last schedule, it's called whenever the job is (re)scheduled, so that
+// the times planned before (like the ones passed while the job is paused) are never treated as fired
+func (d *dynamicSchedule) reset() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.pending = time.Time{}
+	d.probes = make(map[time.Time]bool)
+	d.firing = false
+	d.checks = 0
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// withDynamic make the job scheduled by next
+func withDynamic(name string, next func(last time.Time) (time.Time, error)) JobOption {
+	return func(opt *jobOptions) {
+		opt.dynamic = &dynamicSchedule{name: name, next: next, probes: make(map[time.Time]bool)}
+	}
+}
+
+func (c *schedulerImpl) AddDynamic(name string, next func(last time.Time) (time.Time, error), handler interface{}, options ...JobOption) error {
+	if next == nil {
+		return errors.New("[glacier] next function for dynamic job is nil")
+	}
+
+	_, err := c.add(name, DynamicPlan, handler, append(options, withDynamic(name, next))...)
+	return err
+}
+
+// scheduleDynamic add the dynamic job to cron
+func (c *schedulerImpl) scheduleDynamic(job *Job) cron.EntryID {
+	dynamic := job.options.dynamic
+	dynamic.reset()
+
+	return c.cr.Schedule(dynamic, cron.FuncJob(func() {
+		scheduled := c.scheduledTime(job)
+		if dynamic.consumeProbe(scheduled) {
+			if infra.DEBUG {
+				log.WithFields(infra.Fields{"job": job.Name}).Debugf("[glacier] dynamic job [%s] retries to get next time", job.Name)
+			}
+
+			return
+		}
+
+		exec := &execution{scheduled: scheduled}
+		defer dynamic.fired(scheduled)
+
+		if !c.jitter(job) {
+			return
+		}
+
+		job.run(exec)
+
+		if once := job.options.once; once != nil && once.consume(exec) {
+			c.serially(func() { c.removeOnce(job) })
+		}
+	}))
+}
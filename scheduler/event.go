@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/mylxsw/glacier/event"
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// HeartbeatEvent is published on every heartbeat of scheduler
+type HeartbeatEvent struct {
+	Time time.Time
+}
+
+// JobSkippedEvent is published when a scheduled execution of job is skipped
+type JobSkippedEvent struct {
+	Name   string
+	Reason string
+	Time   time.Time
+}
+
+// JobStartedEvent is published when an execution of job starts, see EnableEventPublishing
+type JobStartedEvent struct {
+	Name  string
+	RunID string
+	Time  time.Time
+}
+
+// JobCompletedEvent is published when an execution of job finishes successfully, see EnableEventPublishing
+type JobCompletedEvent struct {
+	Name     string
+	RunID    string
+	Duration time.Duration
+}
+
+// JobFailedEvent is published when an execution of job fails, see EnableEventPublishing
+type JobFailedEvent struct {
+	Name     string
+	RunID    string
+	Err      error
+	Duration time.Duration
+}
+
+// LeadershipReleasedEvent is published when the distributed locks of jobs are released by PrepareForShutdown
+type LeadershipReleasedEvent struct {
+	Jobs []string
+	Time time.Time
+}
+
+// SchedulerStartedEvent is published when the scheduler is started
+type SchedulerStartedEvent struct {
+	// JobCount is the number of jobs added by users, including the paused ones
+	JobCount    int
+	PausedCount int
+	// HasLock is true when the distributed lock of any job is held, locks are acquired per execution,
+	// so it's always false unless SetWaitForLeadership is used
+	HasLock bool
+	Time    time.Time
+}
+
+// SchedulerStoppedEvent is published when the scheduler is stopped
+type SchedulerStoppedEvent struct {
+	Report ShutdownReport
+	Time   time.Time
+}
+
+func (c *schedulerImpl) EnableEventPublishing(enabled bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.eventPublishing = enabled
+}
+
+// publishRunStarted publish a JobStartedEvent if event publishing is enabled
+func (c *schedulerImpl) publishRunStarted(name string, runID string, startTs time.Time) {
+	c.lock.RLock()
+	enabled := c.eventPublishing
+	c.lock.RUnlock()
+
+	if enabled {
+		c.publish(JobStartedEvent{Name: name, RunID: runID, Time: startTs})
+	}
+}
+
+// publishRunFinished publish a JobCompletedEvent or JobFailedEvent if event publishing is enabled
+func (c *schedulerImpl) publishRunFinished(name string, runID string, err error, d time.Duration) {
+	c.lock.RLock()
+	enabled := c.eventPublishing
+	c.lock.RUnlock()
+
+	if !enabled {
+		return
+	}
+
+	if err != nil {
+		c.publish(JobFailedEvent{Name: name, RunID: runID, Err: err, Duration: d})
+	} else {
+		c.publish(JobCompletedEvent{Name: name, RunID: runID, Duration: d})
+	}
+}
+
+// publish an event through event.Publisher in container, it's best-effort: when no publisher is registered,
+// a warning is logged once and the event is dropped, publishing failures never affect the job execution
+func (c *schedulerImpl) publish(evt interface{}) {
+	var publisher event.Publisher
+	if err := c.resolver.Resolve(func(p event.Publisher) { publisher = p }); err != nil {
+		c.publisherWarning.Do(func() {
+			log.Warningf("[glacier] event publisher is not available, scheduler events will be dropped: %v", err)
+		})
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("[glacier] publish scheduler event %T panic: %v", evt, err)
+		}
+	}()
+
+	if err := publisher.Publish(evt); err != nil {
+		if infra.DEBUG {
+			log.Debugf("[glacier] publish scheduler event %T failed: %v", evt, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// execution is a single execution of job, it carries the scheduled time into the run wrapper, and the
+// outcome out of it, so the scheduling logic can react to what actually happened
+type execution struct {
+	// scheduled is the time planned by cron, it's zero for manual executions
+	scheduled time.Time
+	// ran is true when the handler is called on this node
+	ran bool
+	// skipReason is the reason why the handler is not called, see SkipReasonNotLeader and so on
+	skipReason string
+
+	// guards release the local guards (like mutex group and executor) held by the execution
+	guards []func()
+	// handlers tracks the handler goroutines started by handleWithTimeout
+	handlers sync.WaitGroup
+	// detached is true when the execution timed out while the handler is still running
+	detached bool
+}
+
+// hold register the release function of a local guard, it's called by releaseGuards
+func (exec *execution) hold(release func()) {
+	exec.guards = append(exec.guards, release)
+}
+
+// releaseGuards release the local guards in reverse order. When the handler is still running after
+// timeout, they are held until it returns, so it never overlaps with the next execution of the job
+func (exec *execution) releaseGuards() {
+	release := func() {
+		for i := len(exec.guards) - 1; i >= 0; i-- {
+			exec.guards[i]()
+		}
+	}
+
+	if !exec.detached {
+		release()
+		return
+	}
+
+	go func() {
+		exec.handlers.Wait()
+		release()
+	}()
+}
+
+// scheduledTime return the time planned by cron for the current execution of job, it must be called
+// in the cron job, cron serves Entry after the execution is dispatched, so Prev is the planned time
+func (c *schedulerImpl) scheduledTime(job *Job) time.Time {
+	c.lock.RLock()
+	id := job.ID
+	c.lock.RUnlock()
+
+	return c.cr.Entry(id).Prev
+}
@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// executorPool limits the number of concurrent executions of all jobs, the size can be changed at runtime
+type executorPool struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	size   int
+	active int
+	// maxWait is the max time to wait for a free executor, < 0 means waiting without limit
+	maxWait time.Duration
+}
+
+func newExecutorPool() *executorPool {
+	pool := &executorPool{maxWait: -1}
+	pool.cond = sync.NewCond(&pool.lock)
+
+	return pool
+}
+
+// acquire wait for a free executor at most maxWait, size <= 0 means unlimited.
+// It returns false if no executor is available after that, or ctx is done while waiting
+func (pool *executorPool) acquire(ctx context.Context) bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if pool.size <= 0 || pool.active < pool.size {
+		pool.active++
+		return true
+	}
+
+	if pool.maxWait == 0 {
+		return false
+	}
+
+	// wake the waiters when ctx is done, e.g. the scheduler is stopped
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			pool.lock.Lock()
+			defer pool.lock.Unlock()
+
+			pool.cond.Broadcast()
+		case <-stop:
+		}
+	}()
+
+	var deadline time.Time
+	if pool.maxWait > 0 {
+		deadline = time.Now().Add(pool.maxWait)
+		timer := time.AfterFunc(pool.maxWait, func() {
+			pool.lock.Lock()
+			defer pool.lock.Unlock()
+
+			pool.cond.Broadcast()
+		})
+		defer timer.Stop()
+	}
+
+	for pool.size > 0 && pool.active >= pool.size {
+		if ctx.Err() != nil || (!deadline.IsZero() && !time.Now().Before(deadline)) {
+			return false
+		}
+
+		pool.cond.Wait()
+	}
+
+	// the executor may be released at the same time as ctx is done
+	if ctx.Err() != nil {
+		return false
+	}
+
+	pool.active++
+	return true
+}
+
+func (pool *executorPool) release() {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.active--
+	// waiters may have given up, wake all of them so the free executor is not lost
+	pool.cond.Broadcast()
+}
+
+// resize change the size of pool, when shrinking, the executions in progress are not interrupted,
+// new executions wait until the number of active executions is below the new size
+func (pool *executorPool) resize(size int) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.size = size
+	pool.cond.Broadcast()
+}
+
+func (pool *executorPool) setMaxWait(maxWait time.Duration) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.maxWait = maxWait
+	pool.cond.Broadcast()
+}
+
+func (pool *executorPool) getSize() int {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	return pool.size
+}
+
+// SetExecutorPoolSize limit the number of concurrent executions of all jobs, it can be called at any time.
+// Executions exceeding the limit wait for a free executor (see SetMaxConcurrencyWait), n <= 0 means unlimited
+func (c *schedulerImpl) SetExecutorPoolSize(n int) {
+	c.executors.resize(n)
+}
+
+// SetMaxConcurrency limit the number of concurrent executions of all jobs to n, it's the same limit as
+// SetExecutorPoolSize, the executor pool is shared. n <= 0 means unlimited
+func (c *schedulerImpl) SetMaxConcurrency(n int) {
+	c.executors.resize(n)
+}
+
+// SetMaxConcurrencyWait choose what to do when the concurrency limit is reached: executions wait at most
+// maxWait for a free executor, and are skipped after that. maxWait = 0 means skipping immediately,
+// maxWait < 0 means waiting without limit, which is the default behavior
+func (c *schedulerImpl) SetMaxConcurrencyWait(maxWait time.Duration) {
+	c.executors.setMaxWait(maxWait)
+}
@@ -1,6 +1,9 @@
 package scheduler
 
 import (
+	"errors"
+	"reflect"
+
 	"github.com/mylxsw/glacier/infra"
 )
 
@@ -9,6 +12,46 @@ type JobHandler interface {
 	Handle(resolver infra.Resolver) error
 }
 
+// ResultJobHandler 是可以返回执行结果的 JobHandler，执行结果保存在 JobStats.LastResult 中
+type ResultJobHandler interface {
+	JobHandler
+	HandleWithResult(resolver infra.Resolver) (interface{}, error)
+}
+
+// handle 执行任务处理器，处理器实现了 ResultJobHandler 时同时返回执行结果
+func handle(hh JobHandler, resolver infra.Resolver) (interface{}, error) {
+	if rh, ok := hh.(ResultJobHandler); ok {
+		return rh.HandleWithResult(resolver)
+	}
+
+	return nil, hh.Handle(resolver)
+}
+
+// validateHandler 校验任务处理器是否合法，处理器必须是非 nil 的函数或者 JobHandler 实现
+func validateHandler(handler interface{}) error {
+	if handler == nil {
+		return errors.New("handler is nil")
+	}
+
+	val := reflect.ValueOf(handler)
+	switch val.Kind() {
+	case reflect.Func, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Interface:
+		if val.IsNil() {
+			return errors.New("handler is nil")
+		}
+	}
+
+	if _, ok := handler.(JobHandler); ok {
+		return nil
+	}
+
+	if val.Kind() != reflect.Func {
+		return errors.New("handler must be a function or a JobHandler, got " + val.Type().String())
+	}
+
+	return nil
+}
+
 type jobHandlerImpl struct {
 	handler interface{}
 }
@@ -18,7 +61,44 @@ func newHandler(handler interface{}) JobHandler {
 }
 
 func (h jobHandlerImpl) Handle(resolver infra.Resolver) error {
-	return resolver.Resolve(h.handler)
+	_, err := h.HandleWithResult(resolver)
+	return err
+}
+
+// HandleWithResult 执行任务函数，函数最后一个返回值类型为 error 时作为执行错误，
+// 在它之前的返回值（没有 error 返回值时为最后一个返回值）作为执行结果
+func (h jobHandlerImpl) HandleWithResult(resolver infra.Resolver) (interface{}, error) {
+	results, err := resolver.Call(h.handler)
+	if err != nil {
+		return nil, err
+	}
+
+	return handlerResult(reflect.TypeOf(h.handler), results)
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// handlerResult 从任务函数的返回值中提取执行结果和错误
+func handlerResult(fnType reflect.Type, results []interface{}) (interface{}, error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	last := len(results) - 1
+	if !fnType.Out(last).Implements(errorType) {
+		return results[last], nil
+	}
+
+	var result interface{}
+	if last > 0 {
+		result = results[last-1]
+	}
+
+	if results[last] != nil {
+		return result, results[last].(error)
+	}
+
+	return result, nil
 }
 
 // WithoutOverlap 可以避免当前任务执行时间过长时，同一任务同时存在多个运行实例的问题
@@ -44,15 +124,20 @@ func (handler *OverlapJobHandler) SkipCallback(fn func()) *OverlapJobHandler {
 }
 
 func (handler *OverlapJobHandler) Handle(resolver infra.Resolver) error {
+	_, err := handler.HandleWithResult(resolver)
+	return err
+}
+
+func (handler *OverlapJobHandler) HandleWithResult(resolver infra.Resolver) (interface{}, error) {
 	select {
 	case handler.executing <- struct{}{}:
 		defer func() { <-handler.executing }()
-		return resolver.Resolve(handler.handler)
+		return handle(newHandler(handler.handler), resolver)
 	default:
 		if handler.skipCallback != nil {
 			handler.skipCallback()
 		}
 	}
 
-	return nil
+	return nil, nil
 }
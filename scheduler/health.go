@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+)
+
+// HealthChecker create an infra.HealthChecker for the scheduler in container, it can be used with app.WithHealthCheck
+//
+//	app.WithHealthCheck("/health", scheduler.HealthChecker)
+func HealthChecker(resolver infra.Resolver) infra.HealthChecker {
+	var checker schedulerHealthChecker
+	resolver.MustResolve(func(s Scheduler) { checker.scheduler = s })
+
+	return checker
+}
+
+type schedulerHealthChecker struct {
+	scheduler Scheduler
+}
+
+func (checker schedulerHealthChecker) Name() string {
+	return "scheduler"
+}
+
+func (checker schedulerHealthChecker) CheckHealth(ctx context.Context) error {
+	return checker.scheduler.CheckHealth()
+}
+
+// markStopped mark the scheduler as not running, it's called when the scheduler is stopping
+func (c *schedulerImpl) markStopped() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.running = false
+}
+
+// CheckHealth check whether the scheduler is running, and all active jobs are processed in time.
+//
+// A job is stale when it has neither finished nor been skipped for two scheduled times since its
+// last activity (or since it became active), which means an execution is hanging, or the job is
+// not triggered at all. Dynamic jobs are not checked since their plans are unknown.
+func (c *schedulerImpl) CheckHealth() error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if !c.running {
+		return errors.New("[glacier] scheduler is not running")
+	}
+
+	now := c.clock.Now()
+	stale := make([]string, 0)
+	for _, job := range c.jobs {
+		if job.Internal || job.Paused || job.options.dynamic != nil {
+			continue
+		}
+
+		sc, err := job.schedule()
+		if err != nil {
+			continue
+		}
+
+		last, st := job.addedAt, job.loadState()
+		for _, t := range []time.Time{c.startedAt, st.finishedAt, st.lastSkippedAt} {
+			if t.After(last) {
+				last = t
+			}
+		}
+
+		if deadline := sc.Next(sc.Next(last)); !deadline.IsZero() && now.After(deadline) {
+			stale = append(stale, job.Name)
+		}
+	}
+
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		return fmt.Errorf("[glacier] jobs are not processed in expected interval: %s", strings.Join(stale, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mylxsw/glacier/log"
+)
+
+// DefaultHeartbeatInterval is the default interval of scheduler heartbeat
+const DefaultHeartbeatInterval = 30 * time.Second
+
+func (c *schedulerImpl) SetHeartbeat(interval time.Duration, publishEvent bool) {
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	c.heartbeatInterval = interval
+	c.heartbeatPublishEvent = publishEvent
+}
+
+func (c *schedulerImpl) LastHeartbeat() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.lastHeartbeat
+}
+
+// heartbeatJobName is the name of the internal heartbeat task
+const heartbeatJobName = InternalJobPrefix + "heartbeat"
+
+// startHeartbeat add the heartbeat task as an internal job, the task added by previous Start is kept
+func (c *schedulerImpl) startHeartbeat() {
+	if c.heartbeatInterval <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	var err error
+	if _, ok := c.jobs[heartbeatJobName]; !ok {
+		err = c.addInternalJob(heartbeatJobName, fmt.Sprintf("@every %s", c.heartbeatInterval), c.heartbeat)
+	}
+	c.unlock()
+
+	if err != nil {
+		log.Errorf("[glacier] can not start scheduler heartbeat: %v", err)
+		return
+	}
+
+	c.heartbeat()
+}
+
+func (c *schedulerImpl) heartbeat() {
+	now := c.clock.Now()
+
+	c.lock.Lock()
+	c.lastHeartbeat = now
+	c.lock.Unlock()
+
+	if c.heartbeatPublishEvent {
+		c.publish(HeartbeatEvent{Time: now})
+	}
+}
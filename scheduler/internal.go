@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// InternalJobPrefix is the name prefix reserved for the internal tasks of scheduler, jobs added by users can not use it
+const InternalJobPrefix = "glacier:"
+
+// reservedNameError return an error if name is reserved for internal tasks
+func reservedNameError(name string) error {
+	if strings.HasPrefix(name, InternalJobPrefix) {
+		return errors.Wrapf(ErrInternalJob, "[glacier] job name [%s] uses the reserved prefix %s", name, InternalJobPrefix)
+	}
+
+	return nil
+}
+
+// addInternalJob add a task of scheduler itself as an internal job, the caller must hold the write lock.
+//
+// Internal jobs are excluded from List, Stats, Timeline and Reconcile, and their handlers are called
+// directly: they are not protected by distributed locks, not recorded, and have no execution stats.
+// Their plans are always parsed by the default parser, so SetParserOptions never breaks them.
+func (c *schedulerImpl) addInternalJob(name string, plan string, handler func()) error {
+	if reg, existed := c.jobs[name]; existed {
+		return fmt.Errorf("job with name [%s] already existed: %d | %s", name, reg.ID, reg.Plan)
+	}
+
+	job := &Job{Name: name, ShortName: name, Plan: plan, Internal: true, parser: planParser, state: newJobState(), handler: func() { c.serially(handler) }}
+	id, err := c.schedule(job, plan)
+	if err != nil {
+		return errors.Wrapf(err, "[glacier] add internal job [%s] failed", name)
+	}
+
+	job.ID = id
+	c.jobs[name] = job
+
+	return nil
+}
+
+// serially execute fn, which changes the scheduler by itself (not by users), on the command goroutine
+// if it's a serial scheduler, see NewSerialManager
+func (c *schedulerImpl) serially(fn func()) {
+	if c.serial == nil {
+		fn()
+		return
+	}
+
+	c.serial(fn)
+}
+
+// userJob get the job added by users with name, internal tasks can not be changed by the operations
+// for user jobs (Remove, Pause, UpdatePlan...), the caller must hold the lock
+func (c *schedulerImpl) userJob(name string) (*Job, error) {
+	job, ok := c.jobs[name]
+	if !ok {
+		return nil, jobNotFoundError(name)
+	}
+
+	if job.Internal {
+		return nil, errors.Wrapf(ErrInternalJob, "[glacier] job with name [%s]", name)
+	}
+
+	return job, nil
+}
+
+func (c *schedulerImpl) ListInternal() []Job {
+	internal := c.snapshot.Load().internal
+
+	jobs := make([]Job, len(internal))
+	for i, job := range internal {
+		jobs[i] = job.withState()
+	}
+
+	return jobs
+}
@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// jitter block the scheduled execution of job for a random duration in [0, jitter] set by WithJitter,
+// it returns false if the scheduler is stopped during the delay, the execution should be abandoned then
+func (c *schedulerImpl) jitter(job *Job) bool {
+	if job.options.jitter <= 0 {
+		return true
+	}
+
+	delay := time.Duration(rand.Int63n(int64(job.options.jitter) + 1))
+	if infra.DEBUG {
+		log.WithFields(infra.Fields{"job": job.Name, "delay": delay}).Debugf("[glacier] cron job [%s] delayed %s by jitter", job.Name, delay)
+	}
+
+	return c.delay(job, delay)
+}
+
+// delay block the execution of job for d, it returns false if the scheduler is stopped before that
+func (c *schedulerImpl) delay(job *Job, d time.Duration) bool {
+	c.lock.RLock()
+	ctx := c.baseCtx
+	c.lock.RUnlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		if infra.DEBUG {
+			log.WithFields(infra.Fields{"job": job.Name}).Debugf("[glacier] delayed execution of cron job [%s] is abandoned because scheduler is stopped", job.Name)
+		}
+
+		return false
+	}
+}
@@ -0,0 +1,269 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+)
+
+// JobOption 定时任务配置项，在添加定时任务时指定
+type JobOption func(opt *jobOptions)
+
+type jobOptions struct {
+	// err is the error occurred when applying options, it will be returned when adding the job
+	err error
+
+	namespace   string
+	mutexGroup  string
+	activeHours *activeHours
+
+	backoffBase time.Duration
+	backoffMax  time.Duration
+
+	initialDelay time.Duration
+	minInterval  time.Duration
+	timeout      time.Duration
+	jitter       time.Duration
+	runAfter     []string
+	maxRetries   int
+	retryBackoff time.Duration
+	dynamic      *dynamicSchedule
+	once         *oneShot
+
+	webhookOnSuccess string
+	webhookOnFailure string
+
+	runLock       bool
+	skipIfRunning bool
+	ignoreLock    bool
+	tags          []string
+	locale        string
+	location      *time.Location
+}
+
+func newJobOptions(options ...JobOption) jobOptions {
+	opt := jobOptions{}
+	for _, o := range options {
+		o(&opt)
+	}
+
+	return opt
+}
+
+// WithMutexGroup 设置任务的互斥组，同一个互斥组中的任务同一时间只会有一个在执行
+// 当任务无法获取到互斥组的锁时，本次调度将会被跳过
+func WithMutexGroup(group string) JobOption {
+	return func(opt *jobOptions) {
+		opt.mutexGroup = group
+	}
+}
+
+// WithTags 为任务添加标签
+func WithTags(tags ...string) JobOption {
+	return func(opt *jobOptions) {
+		opt.tags = append(opt.tags, tags...)
+	}
+}
+
+// WithActiveHours 限制任务只在指定的时间窗口内执行，窗口外的调度将会被跳过
+// days 为空时表示每天，start 和 end 的格式为 15:04，end 小于 start 时表示跨越午夜，loc 为 nil 时使用本地时区
+func WithActiveHours(days []time.Weekday, start, end string, loc *time.Location) JobOption {
+	return func(opt *jobOptions) {
+		hours, err := newActiveHours(days, start, end, loc)
+		if err != nil {
+			opt.err = err
+			return
+		}
+
+		opt.activeHours = hours
+	}
+}
+
+// DefaultFailureBackoffMax is the max backoff of WithFailureBackoff when max is not given
+const DefaultFailureBackoffMax = 24 * time.Hour
+
+// WithFailureBackoff 任务执行失败后，在退避时间内的调度将会被跳过，退避时间为 base * 2^(连续失败次数-1)，最大为 max，
+// max <= 0 时最大为 DefaultFailureBackoffMax（base 更大时为 base）。任务执行成功后恢复正常调度
+func WithFailureBackoff(base, max time.Duration) JobOption {
+	return func(opt *jobOptions) {
+		opt.backoffBase = base
+		opt.backoffMax = max
+	}
+}
+
+// failureBackoff calculate the backoff duration for consecutive failures
+func (opt jobOptions) failureBackoff(failures int64) time.Duration {
+	if opt.backoffBase <= 0 || failures <= 0 {
+		return 0
+	}
+
+	max := opt.backoffMax
+	if max <= 0 {
+		max = DefaultFailureBackoffMax
+		if opt.backoffBase > max {
+			max = opt.backoffBase
+		}
+	}
+
+	// the doubling stops at max, so it never overflows however many failures there are
+	backoff := opt.backoffBase
+	for i := int64(1); i < failures && backoff < max; i++ {
+		backoff *= 2
+	}
+
+	if backoff > max {
+		return max
+	}
+
+	return backoff
+}
+
+// WithRunLock 任务每次执行期间持有一个独立的分布式锁（名称为 `任务名#run`），执行完毕后释放
+// 这样即使进程重启，新的实例也不会在旧实例的执行结束（或者锁过期）之前再次执行该任务，需要配合 SetLockManagerOption 使用
+func WithRunLock() JobOption {
+	return func(opt *jobOptions) {
+		opt.runLock = true
+	}
+}
+
+type activeHours struct {
+	days       map[time.Weekday]bool
+	start, end int
+	loc        *time.Location
+}
+
+func newActiveHours(days []time.Weekday, start, end string, loc *time.Location) (*activeHours, error) {
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid active hours start %s: %v", start, err)
+	}
+
+	endTime, err := time.Parse("15:04", end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid active hours end %s: %v", end, err)
+	}
+
+	if loc == nil {
+		loc = time.Local
+	}
+
+	hours := &activeHours{
+		days:  make(map[time.Weekday]bool),
+		start: startTime.Hour()*60 + startTime.Minute(),
+		end:   endTime.Hour()*60 + endTime.Minute(),
+		loc:   loc,
+	}
+	for _, day := range days {
+		hours.days[day] = true
+	}
+
+	return hours, nil
+}
+
+// contains check whether t is in the active hours
+func (h *activeHours) contains(t time.Time) bool {
+	t = t.In(h.loc)
+	if len(h.days) > 0 && !h.days[t.Weekday()] {
+		return false
+	}
+
+	minutes := t.Hour()*60 + t.Minute()
+	if h.start <= h.end {
+		return minutes >= h.start && minutes < h.end
+	}
+
+	return minutes >= h.start || minutes < h.end
+}
+
+// WithLocale 为任务设置区域（如 zh-CN、en-US），任务函数可以声明 context.Context 参数，通过 LocaleFromContext 获取
+func WithLocale(locale string) JobOption {
+	return func(opt *jobOptions) {
+		opt.locale = locale
+	}
+}
+
+// WithIgnoreLock 任务不使用分布式锁，在所有节点上都会执行，适用于清理本地缓存等每个节点都需要执行的任务
+// 只忽略调度时获取的分布式锁，如果同时指定了 WithRunLock，每次执行时依然需要获取执行锁
+func WithIgnoreLock() JobOption {
+	return func(opt *jobOptions) {
+		opt.ignoreLock = true
+	}
+}
+
+// WithInitialDelay 调度器启动后（任务在启动后添加时，从添加时开始）的 d 时间内，任务的调度将会被跳过，之后按照正常的执行计划执行
+func WithInitialDelay(d time.Duration) JobOption {
+	return func(opt *jobOptions) {
+		opt.initialDelay = d
+	}
+}
+
+// WithWebhook 任务每次执行完毕后，将执行结果（WebhookPayload）以 JSON 格式 POST 到 onSuccess 或 onFailure 地址
+// 地址为空时不发送，发送过程是异步的，超时时间为 10s，发送失败时仅记录日志，不影响任务执行
+func WithWebhook(onSuccess, onFailure string) JobOption {
+	return func(opt *jobOptions) {
+		opt.webhookOnSuccess = onSuccess
+		opt.webhookOnFailure = onFailure
+	}
+}
+
+// WithMinInterval 限制任务上一次执行结束到下一次执行开始的最小间隔，间隔不足时本次调度将会被跳过
+// 任务正在执行时（包括通过 Trigger 触发的执行），新的调度同样会被跳过
+func WithMinInterval(d time.Duration) JobOption {
+	return func(opt *jobOptions) {
+		opt.minInterval = d
+	}
+}
+
+// WithSkipIfRunning 任务的上一次执行还没有结束时，跳过本次调度，避免同一任务同时存在多个执行实例
+// 任务执行期间依然可以暂停或者移除任务，不会影响正在进行中的执行
+func WithSkipIfRunning() JobOption {
+	return func(opt *jobOptions) {
+		opt.skipIfRunning = true
+	}
+}
+
+// WithTimeout 设置任务单次执行的超时时间（包括 WithRetry 的重试），任务函数可以声明 context.Context 参数，超时后 context 将会被取消
+// 超时后调度器不再等待任务函数返回，本次执行记录为失败，并释放 WithRunLock 的分布式锁，任务函数需要自行响应 context 的取消
+// 任务函数返回之前，SkipIfRunning，互斥组，标签并发数，执行器以及 RunScope 等本地资源依然被占用，任务的下一次执行不会与其重叠
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(opt *jobOptions) {
+		opt.timeout = timeout
+	}
+}
+
+// WithLocation 设置任务执行计划使用的时区，默认使用服务器本地时区
+// 执行计划中通过 CRON_TZ=America/New_York 前缀显式指定的时区优先于该选项
+func WithLocation(loc *time.Location) JobOption {
+	return func(opt *jobOptions) {
+		opt.location = loc
+	}
+}
+
+// WithRetry 任务执行返回错误（或者 panic）后，在本次调度中最多重试 maxRetries 次，第 n 次重试前等待 backoff * 2^(n-1)
+// 设置了 WithTimeout 时，所有重试共享同一个超时时间，剩余时间不足以等待下一次重试时不再重试
+// 所有重试共享同一个 context，调度器停止或者任务的分布式锁丢失导致 context 被取消后不再重试
+func WithRetry(maxRetries int, backoff time.Duration) JobOption {
+	return func(opt *jobOptions) {
+		opt.maxRetries = maxRetries
+		opt.retryBackoff = backoff
+	}
+}
+
+// WithJitter 每次调度执行前随机等待 [0, jitter] 时间，避免多个实例同时启动时任务在同一时刻执行
+// 随机等待发生在分布式锁检查之前，只对调度触发的执行生效，AddAndRunOnServerReady 的启动执行和 Trigger 不会等待
+// 同时设置了 SetTickSpread 时，随机等待在错峰延迟之后叠加
+func WithJitter(jitter time.Duration) JobOption {
+	return func(opt *jobOptions) {
+		opt.jitter = jitter
+	}
+}
+
+// WithRunAfter 设置任务的前置任务（任务的完整名称），只有所有前置任务在当前周期内都执行成功后，任务才会执行，否则本次调度将会被跳过
+// 当前周期是指从任务上一次被调度（首次调度时为调度器启动或任务添加时）到本次调度之间的时间，在此期间前置任务最后一次执行需要已经结束并且成功
+// 前置任务的执行计划与任务不同时：前置任务执行更频繁时，以周期内前置任务最后一次执行的结果为准；前置任务执行频率更低时，
+// 没有前置任务执行的周期内任务将会被跳过，因此通常应该让任务的执行时间晚于前置任务
+// 前置任务需要在添加任务之前添加（Reconcile 时需要在期望的任务列表中），不能是任务自身，否则添加任务失败
+func WithRunAfter(names ...string) JobOption {
+	return func(opt *jobOptions) {
+		opt.runAfter = append(opt.runAfter, names...)
+	}
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelNamePattern is the valid name of labels in OpenMetrics
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are the labels added by scheduler to metrics, they can not be used by SetLabels
+var reservedLabels = map[string]bool{"job": true, "le": true}
+
+func (c *schedulerImpl) SetLabels(labels map[string]string) error {
+	for k := range labels {
+		if !labelNamePattern.MatchString(k) || strings.HasPrefix(k, "__") {
+			return fmt.Errorf("[glacier] invalid label name [%s]", k)
+		}
+
+		if reservedLabels[k] {
+			return fmt.Errorf("[glacier] label name [%s] is reserved by scheduler", k)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.labels = make(map[string]string, len(labels))
+	for k, v := range labels {
+		c.labels[k] = v
+	}
+
+	return nil
+}
+
+// getLabels return the labels of scheduler, the returned map must not be modified
+func (c *schedulerImpl) getLabels() map[string]string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.labels
+}
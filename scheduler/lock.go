@@ -0,0 +1,254 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// DefaultLockRefreshInterval is the default interval to refresh the distributed locks held by jobs
+const DefaultLockRefreshInterval = 60 * time.Second
+
+// lockRefreshJobName is the name of the internal task refreshing distributed locks
+const lockRefreshJobName = InternalJobPrefix + "lock-refresh"
+
+// JobLockAcquiredEvent is published when the distributed lock of job is acquired, the node becomes the leader of job
+type JobLockAcquiredEvent struct {
+	Name string
+	Time time.Time
+}
+
+// JobLockLostEvent is published when the distributed lock held by job is lost, the node is no longer the leader of job
+type JobLockLostEvent struct {
+	Name string
+	Err  error
+	Time time.Time
+}
+
+// lockTracker tracks the distributed locks held by jobs, and the cancel functions of their runs in progress
+type lockTracker struct {
+	lock     sync.Mutex
+	held     map[string]bool
+	runs     map[string]map[*context.CancelFunc]bool
+	handlers []func(name string)
+}
+
+func newLockTracker() *lockTracker {
+	return &lockTracker{held: make(map[string]bool), runs: make(map[string]map[*context.CancelFunc]bool)}
+}
+
+// acquired mark the lock of job as held, return true if it's not held before
+func (t *lockTracker) acquired(name string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.held[name] {
+		return false
+	}
+
+	t.held[name] = true
+	return true
+}
+
+// lost mark the lock of job as not held, return the handlers and the cancel functions of runs in progress
+// if the lock is held before, otherwise the lock is never held and nothing is lost
+func (t *lockTracker) lost(name string) (bool, []func(name string), []context.CancelFunc) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.held[name] {
+		return false, nil, nil
+	}
+
+	delete(t.held, name)
+
+	cancels := make([]context.CancelFunc, 0, len(t.runs[name]))
+	for cancel := range t.runs[name] {
+		cancels = append(cancels, *cancel)
+	}
+
+	return true, append([]func(name string){}, t.handlers...), cancels
+}
+
+// forget mark the lock of job as not held without notifying anyone, it's used when the lock is released
+func (t *lockTracker) forget(name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.held, name)
+}
+
+// holding return the names of jobs whose lock is held
+func (t *lockTracker) holding() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	names := make([]string, 0, len(t.held))
+	for name := range t.held {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// track register the cancel function of a run of job, it's called when the lock of job is lost.
+// The returned function must be called instead of cancel after the run
+func (t *lockTracker) track(name string, cancel context.CancelFunc) context.CancelFunc {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.runs[name] == nil {
+		t.runs[name] = make(map[*context.CancelFunc]bool)
+	}
+
+	key := &cancel
+	t.runs[name][key] = true
+
+	return func() {
+		t.lock.Lock()
+		delete(t.runs[name], key)
+		if len(t.runs[name]) == 0 {
+			delete(t.runs, name)
+		}
+		t.lock.Unlock()
+
+		cancel()
+	}
+}
+
+func (c *schedulerImpl) SetLockRefreshInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultLockRefreshInterval
+	}
+
+	c.lock.Lock()
+	defer c.unlock()
+
+	c.lockRefreshInterval = interval
+
+	// the refresh task has been started, reschedule it with the new interval
+	if job, ok := c.jobs[lockRefreshJobName]; ok {
+		if err := c.reschedule(job, lockRefreshPlan(interval)); err != nil {
+			log.Errorf("[glacier] can not change distributed lock refresh interval: %v", err)
+		}
+	}
+}
+
+// lockRefreshPlan return the plan of lock refresh task, interval less than or equal to 0 means the default one
+func lockRefreshPlan(interval time.Duration) string {
+	if interval <= 0 {
+		interval = DefaultLockRefreshInterval
+	}
+
+	return fmt.Sprintf("@every %s", interval)
+}
+
+func (c *schedulerImpl) OnLockLost(handler func(name string)) {
+	c.locks.lock.Lock()
+	defer c.locks.lock.Unlock()
+
+	c.locks.handlers = append(c.locks.handlers, handler)
+}
+
+// lockAcquired is called when TryLock of job succeeds
+func (c *schedulerImpl) lockAcquired(name string) {
+	if !c.locks.acquired(name) {
+		return
+	}
+
+	if infra.DEBUG {
+		log.WithFields(infra.Fields{"job": name}).Debugf("[glacier] cron job [%s] acquired the distributed lock", name)
+	}
+
+	c.publish(JobLockAcquiredEvent{Name: name, Time: c.clock.Now()})
+}
+
+// lockFailed is called when TryLock of job fails with ErrLockFailed, if the lock is held before, the runs of job in progress are
+// cancelled, and the handlers registered by OnLockLost are called
+func (c *schedulerImpl) lockFailed(name string, err error) {
+	lost, handlers, cancels := c.locks.lost(name)
+	if !lost {
+		return
+	}
+
+	log.WithFields(infra.Fields{"job": name, "error": err, "cancelled_runs": len(cancels)}).Errorf("[glacier] cron job [%s] lost the distributed lock", name)
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	c.publish(JobLockLostEvent{Name: name, Err: err, Time: c.clock.Now()})
+
+	for _, handler := range handlers {
+		c.callLockLostHandler(handler, name)
+	}
+}
+
+func (c *schedulerImpl) callLockLostHandler(handler func(name string), name string) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.WithFields(infra.Fields{"job": name, "error": err}).Errorf("[glacier] lock lost handler for cron job [%s] panic: %v", name, err)
+		}
+	}()
+
+	handler(name)
+}
+
+// startLockRefresh add the internal task refreshing the distributed locks held by jobs, so that the lock
+// loss is detected even if the job is running or not scheduled for a long time
+func (c *schedulerImpl) startLockRefresh() {
+	c.lock.Lock()
+	defer c.unlock()
+
+	c.addLockRefresh()
+}
+
+// addLockRefresh add the lock refresh task if the lock manager is set, the caller must hold the write lock
+func (c *schedulerImpl) addLockRefresh() {
+	if c.lockManagerBuilder == nil {
+		return
+	}
+
+	if _, ok := c.jobs[lockRefreshJobName]; ok {
+		return
+	}
+
+	if err := c.addInternalJob(lockRefreshJobName, lockRefreshPlan(c.lockRefreshInterval), c.refreshLocks); err != nil {
+		log.Errorf("[glacier] can not start distributed lock refreshing: %v", err)
+	}
+}
+
+// refreshLocks refresh the distributed locks held by jobs.
+//
+// Only ErrLockFailed means the lock is lost (held by others). Other errors (e.g. the lock server is not
+// reachable) can not tell whether the lock is still held, they are only logged, and the lock is treated
+// as held until a later TryLock returns ErrLockFailed, so that a transient error never cancels the runs.
+func (c *schedulerImpl) refreshLocks() {
+	for _, name := range c.locks.holding() {
+		c.lock.RLock()
+		var lockManager LockManager
+		if job, ok := c.jobs[name]; ok {
+			lockManager = job.lockManager
+		}
+		c.lock.RUnlock()
+
+		if lockManager == nil {
+			c.locks.forget(name)
+			continue
+		}
+
+		if err := lockManager.TryLock(context.TODO()); err != nil {
+			if errors.Is(err, ErrLockFailed) {
+				c.lockFailed(name, err)
+				continue
+			}
+
+			log.WithFields(infra.Fields{"job": name, "error": err}).Errorf("[glacier] cron job [%s] can not refresh the distributed lock", name)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+)
+
+// MetricsContentType is the content type of the metrics written by WriteMetrics
+const MetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// metricsLabelEscaper escape label values as required by OpenMetrics text format
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics write the stats of scheduler in OpenMetrics text format, it can be served by a http handler
+// with MetricsContentType directly. Only the job name is used as label, and internal jobs are excluded,
+// so the cardinality is bounded by the number of registered jobs.
+func (c *schedulerImpl) WriteMetrics(w io.Writer) error {
+	jobs := c.List()
+
+	var paused, runs int
+	for _, job := range jobs {
+		if job.Paused {
+			paused++
+		}
+
+		runs += job.Stats.Running
+	}
+
+	labels := c.getLabels()
+
+	var buf bytes.Buffer
+	writeMetricsFamily(&buf, "glacier_scheduler_jobs", "gauge", "Number of jobs")
+	fmt.Fprintf(&buf, "glacier_scheduler_jobs%s %d\n", formatMetricsLabels(labels, ""), len(jobs))
+	writeMetricsFamily(&buf, "glacier_scheduler_paused_jobs", "gauge", "Number of paused jobs")
+	fmt.Fprintf(&buf, "glacier_scheduler_paused_jobs%s %d\n", formatMetricsLabels(labels, ""), paused)
+	writeMetricsFamily(&buf, "glacier_scheduler_active_runs", "gauge", "Number of executions in progress")
+	fmt.Fprintf(&buf, "glacier_scheduler_active_runs%s %d\n", formatMetricsLabels(labels, ""), runs)
+
+	writeMetricsFamily(&buf, "glacier_scheduler_job_runs", "counter", "Number of finished executions of job")
+	for _, job := range jobs {
+		fmt.Fprintf(&buf, "glacier_scheduler_job_runs_total%s %d\n", formatMetricsLabels(labels, job.Name), job.Stats.RunCount)
+	}
+
+	writeMetricsFamily(&buf, "glacier_scheduler_job_failures", "counter", "Number of failed executions of job")
+	for _, job := range jobs {
+		fmt.Fprintf(&buf, "glacier_scheduler_job_failures_total%s %d\n", formatMetricsLabels(labels, job.Name), job.Stats.FailureCount)
+	}
+
+	writeMetricsFamily(&buf, "glacier_scheduler_job_duration_seconds", "histogram", "Duration of finished executions of job")
+	for _, job := range jobs {
+		job.Stats.durations.write(&buf, "glacier_scheduler_job_duration_seconds", labels, job.Name)
+	}
+
+	buf.WriteString("# EOF\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// durationBuckets is the upper bounds (in seconds) of buckets of the duration histogram
+var durationBuckets = [...]float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600}
+
+// durationHistogram is a histogram of execution durations, it's a value type so that it can be copied with Job
+type durationHistogram struct {
+	buckets [len(durationBuckets)]int64
+	count   int64
+	sum     float64
+}
+
+func (h *durationHistogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	for i, le := range durationBuckets {
+		if seconds <= le {
+			h.buckets[i]++
+		}
+	}
+
+	h.count++
+	h.sum += seconds
+}
+
+func (h durationHistogram) write(buf *bytes.Buffer, name string, labels map[string]string, job string) {
+	for i, le := range durationBuckets {
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", name, formatMetricsLabels(withMetricsLabel(labels, "le", strconv.FormatFloat(le, 'g', -1, 64)), job), h.buckets[i])
+	}
+
+	fmt.Fprintf(buf, "%s_bucket%s %d\n", name, formatMetricsLabels(withMetricsLabel(labels, "le", "+Inf"), job), h.count)
+	fmt.Fprintf(buf, "%s_count%s %d\n", name, formatMetricsLabels(labels, job), h.count)
+	fmt.Fprintf(buf, "%s_sum%s %s\n", name, formatMetricsLabels(labels, job), strconv.FormatFloat(h.sum, 'g', -1, 64))
+}
+
+// withMetricsLabel return a copy of labels with an extra label
+func withMetricsLabel(labels map[string]string, key, value string) map[string]string {
+	res := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		res[k] = v
+	}
+
+	res[key] = value
+	return res
+}
+
+// formatMetricsLabels format the labels of scheduler and the job name (if not empty) as {k="v",...}
+func formatMetricsLabels(labels map[string]string, job string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", k, metricsLabelEscaper.Replace(labels[k])))
+	}
+
+	if job != "" {
+		pairs = append(pairs, fmt.Sprintf("job=\"%s\"", metricsLabelEscaper.Replace(job)))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func writeMetricsFamily(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# TYPE %s %s\n# HELP %s %s.\n", name, typ, name, help)
+}
+
+// MetricsCollector create an infra.MetricsCollector for the scheduler in container, it can be used with app.WithPrometheus
+//
+//	app.WithPrometheus("/metrics", scheduler.MetricsCollector)
+func MetricsCollector(resolver infra.Resolver) infra.MetricsCollector {
+	var collector schedulerMetricsCollector
+	resolver.MustResolve(func(s Scheduler) { collector.scheduler = s })
+
+	return collector
+}
+
+type schedulerMetricsCollector struct {
+	scheduler Scheduler
+}
+
+func (collector schedulerMetricsCollector) CollectMetrics(w io.Writer) error {
+	var buf bytes.Buffer
+	if err := collector.scheduler.WriteMetrics(&buf); err != nil {
+		return err
+	}
+
+	_, err := w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("# EOF\n")))
+	return err
+}
@@ -0,0 +1,34 @@
+package scheduler
+
+// Middleware wrap the execution of job, it must call next to run the job, or skip the execution
+// by returning without calling it
+type Middleware func(next func(), job Job) func()
+
+// Use register middlewares for all jobs, the first registered middleware is the outermost one.
+//
+// Middlewares are applied on every execution, after the execution passed all checks of scheduler
+// (distributed lock, mutex group, concurrency limits, etc.), so they only run on the node which
+// actually executes the job. They are called inside the panic recovery of scheduler, a panicking
+// middleware fails the execution like a panicking job. The run starts when next is called, an execution
+// short-circuited by middleware is not counted as a run, it's recorded as skipped (SkipReasonMiddleware)
+// and a JobSkippedEvent is published.
+func (c *schedulerImpl) Use(middlewares ...Middleware) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// applyMiddlewares wrap handler with the registered middlewares
+func (c *schedulerImpl) applyMiddlewares(job *Job, handler func()) func() {
+	c.lock.RLock()
+	middlewares := c.middlewares
+	info := *job
+	c.lock.RUnlock()
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler, info)
+	}
+
+	return handler
+}
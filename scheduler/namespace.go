@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// namespacedCreator is a JobCreator which adds jobs into a namespace
+type namespacedCreator struct {
+	namespace string
+	creator   JobCreator
+}
+
+func (c *schedulerImpl) Namespace(namespace string) JobCreator {
+	return &namespacedCreator{namespace: namespace, creator: c}
+}
+
+func (n *namespacedCreator) name(name string) string {
+	return n.namespace + "." + name
+}
+
+func (n *namespacedCreator) options(options []JobOption) []JobOption {
+	return append([]JobOption{withNamespace(n.namespace)}, options...)
+}
+
+func (n *namespacedCreator) Add(name string, plan string, handler interface{}, options ...JobOption) error {
+	return n.creator.Add(n.name(name), plan, handler, n.options(options)...)
+}
+
+func (n *namespacedCreator) AddAndReturnID(name string, plan string, handler interface{}, options ...JobOption) (cron.EntryID, error) {
+	return n.creator.AddAndReturnID(n.name(name), plan, handler, n.options(options)...)
+}
+
+func (n *namespacedCreator) AddOrReplace(name string, plan string, handler interface{}, options ...JobOption) error {
+	return n.creator.AddOrReplace(n.name(name), plan, handler, n.options(options)...)
+}
+
+func (n *namespacedCreator) AddAndRunOnServerReady(name string, plan string, handler interface{}, options ...JobOption) error {
+	return n.creator.AddAndRunOnServerReady(n.name(name), plan, handler, n.options(options)...)
+}
+
+func (n *namespacedCreator) MustAdd(name string, plan string, handler interface{}, options ...JobOption) {
+	n.creator.MustAdd(n.name(name), plan, handler, n.options(options)...)
+}
+
+func (n *namespacedCreator) MustAddAndRunOnServerReady(name string, plan string, handler interface{}, options ...JobOption) {
+	n.creator.MustAddAndRunOnServerReady(n.name(name), plan, handler, n.options(options)...)
+}
+
+func (n *namespacedCreator) AddDynamic(name string, next func(last time.Time) (time.Time, error), handler interface{}, options ...JobOption) error {
+	return n.creator.AddDynamic(n.name(name), next, handler, n.options(options)...)
+}
+
+func (n *namespacedCreator) RunAt(name string, t time.Time, handler interface{}, options ...JobOption) error {
+	return n.creator.RunAt(n.name(name), t, handler, n.options(options)...)
+}
+
+func (n *namespacedCreator) RegisterStruct(v interface{}) error {
+	return registerStruct(n, v)
+}
+
+func (n *namespacedCreator) Namespace(namespace string) JobCreator {
+	return &namespacedCreator{namespace: n.name(namespace), creator: n.creator}
+}
+
+// withNamespace set the namespace of job, it is used by namespacedCreator
+func withNamespace(namespace string) JobOption {
+	return func(opt *jobOptions) {
+		opt.namespace = namespace
+	}
+}
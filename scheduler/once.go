@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// OncePlan is the plan of jobs added by RunAt
+const OncePlan = "@once"
+
+// oneShot is the state of job added by RunAt
+type oneShot struct {
+	lock sync.Mutex
+	at   time.Time
+	done bool
+}
+
+// consume mark the one-shot job as done if the execution ran on this node, or it's skipped because
+// another node holds the lock (the job runs there), or a middleware decided not to run it. For other
+// skipped executions (like the ones skipped by mutex group or concurrency limits), the job is kept and
+// retried as soon as possible
+func (o *oneShot) consume(exec *execution) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if exec.ran || exec.skipReason == SkipReasonNotLeader || exec.skipReason == SkipReasonMiddleware {
+		o.done = true
+	}
+
+	return o.done
+}
+
+func (o *oneShot) isDone() bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return o.done
+}
+
+// withRunAt make the job a one-shot job executed at t
+func withRunAt(once *oneShot) JobOption {
+	return func(opt *jobOptions) {
+		opt.once = once
+	}
+}
+
+func (c *schedulerImpl) RunAt(name string, t time.Time, handler interface{}, options ...JobOption) error {
+	once := &oneShot{at: t}
+	next := func(last time.Time) (time.Time, error) {
+		if once.isDone() {
+			return time.Time{}, nil
+		}
+
+		// the time in the past (including the one passed while the job is paused, or the execution
+		// is skipped) is moved to the next second by the dynamic schedule
+		return t, nil
+	}
+
+	_, err := c.add(name, OncePlan, handler, append(options, withDynamic(name, next), withRunAt(once))...)
+	return err
+}
+
+// removeOnce remove the one-shot job after it's executed, the job is kept if it has been replaced by another one with the same name
+func (c *schedulerImpl) removeOnce(job *Job) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	if c.jobs[job.Name] != job {
+		return
+	}
+
+	if err := c.removeJob(job.Name); err != nil {
+		log.WithFields(infra.Fields{"job": job.Name, "error": err}).Errorf("[glacier] remove one-shot job [%s] failed: %v", job.Name, err)
+		return
+	}
+
+	if infra.DEBUG {
+		log.WithFields(infra.Fields{"job": job.Name}).Debugf("[glacier] one-shot job [%s] has been executed and removed", job.Name)
+	}
+}
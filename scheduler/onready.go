@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// SetOnReadyLockTimeout set the max time to wait for the distributed lock before the on-ready run of jobs
+// added by AddAndRunOnServerReady. When the lock is not acquired in time, the on-ready run is abandoned, and
+// the job runs on its normal schedule once the lock is acquired. timeout <= 0 means try the lock only once.
+func (c *schedulerImpl) SetOnReadyLockTimeout(timeout time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.onReadyLockTimeout = timeout
+}
+
+// onReadyHandler wrap the handler for on-ready run with a bounded wait for the distributed lock
+func (c *schedulerImpl) onReadyHandler(name string, handler func()) func() {
+	return func() {
+		c.lock.RLock()
+		timeout := c.onReadyLockTimeout
+		var lockManager LockManager
+		if job, ok := c.jobs[name]; ok {
+			lockManager = job.lockManager
+		}
+		c.lock.RUnlock()
+
+		if lockManager != nil && timeout > 0 && !waitForLock(lockManager, timeout) {
+			if infra.WARN {
+				log.WithFields(infra.Fields{"job": name, "timeout": timeout}).Warningf("[glacier] on-ready run of cron job [%s] is abandoned because its lock is not acquired in %s, it will run on its normal schedule", name, timeout)
+			}
+
+			return
+		}
+
+		handler()
+	}
+}
+
+// waitForLock try to acquire the lock until success or timeout
+func waitForLock(lockManager LockManager, timeout time.Duration) bool {
+	startTs := time.Now()
+	for {
+		if err := lockManager.TryLock(context.TODO()); err == nil {
+			return true
+		}
+
+		if time.Since(startTs) >= timeout {
+			return false
+		}
+
+		time.Sleep(minDuration(time.Second, timeout-time.Since(startTs)))
+	}
+}
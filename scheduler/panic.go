@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// PanicFormatter convert the value recovered from a panicking job into an error, which is stored as the
+// result of the execution. It's called after the PanicHandler, use PanicHandler to report the panic.
+type PanicFormatter func(name string, recovered interface{}, stack []byte) error
+
+// PanicHandler is called with the job, the recovered value and the stack when a job panics, it's called in
+// addition to the default error log, so the panic can be reported to an error tracker like Sentry.
+//
+// When both are set, the PanicHandler is always called first, then the PanicFormatter converts the panic
+// into the error of the execution, a panicking handler never prevents the formatter from being called
+type PanicHandler func(job Job, recovered interface{}, stack []byte)
+
+func (c *schedulerImpl) SetPanicHandler(handler PanicHandler) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.panicHandler = handler
+}
+
+// recoverPanic call the panic handler of scheduler, and convert the recovered value into an error by formatPanic
+func (c *schedulerImpl) recoverPanic(job *Job, recovered interface{}, stack []byte) error {
+	c.lock.RLock()
+	handler := c.panicHandler
+	info := *job
+	c.lock.RUnlock()
+
+	if handler != nil {
+		safeCallback(job.Name, func() { handler(info, recovered, stack) })
+	}
+
+	return c.formatPanic(job.Name, recovered, stack)
+}
+
+func (c *schedulerImpl) SetPanicFormatter(formatter PanicFormatter) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.panicFormatter = formatter
+}
+
+// formatPanic convert the recovered value into an error by the panic formatter, when no formatter is
+// registered (or the formatter panics), a "panic: xxx" error is returned
+func (c *schedulerImpl) formatPanic(name string, recovered interface{}, stack []byte) (err error) {
+	c.lock.RLock()
+	formatter := c.panicFormatter
+	c.lock.RUnlock()
+
+	defaultErr := fmt.Errorf("panic: %v", recovered)
+	if formatter == nil {
+		return defaultErr
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			log.WithFields(infra.Fields{"job": name, "error": e}).Errorf("[glacier] panic formatter for cron job [%s] panic: %v", name, e)
+			err = defaultErr
+		}
+	}()
+
+	if err = formatter(name, recovered, stack); err == nil {
+		err = defaultErr
+	}
+
+	return err
+}
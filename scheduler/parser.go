@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"time"
+
+	cron "github.com/robfig/cron/v3"
+)
+
+// CronMode is the mode used to interpret the plan of jobs
+type CronMode int
+
+const (
+	// CronWithSeconds interpret plans as 6 fields with seconds, e.g. "*/5 * * * * *", this is the default mode
+	CronWithSeconds CronMode = iota
+	// CronStandard interpret plans as standard 5 fields crontab, e.g. "*/5 * * * *"
+	CronStandard
+)
+
+// planParser is the parser for job plans in default mode (CronWithSeconds)
+var planParser = CronWithSeconds.parser()
+
+// parser create a cron parser for the mode, descriptors like "@every 10s" are supported in all modes
+func (mode CronMode) parser() cron.Parser {
+	if mode == CronStandard {
+		return cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	}
+
+	return cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+}
+
+// schedule get the schedule of job, the plan is parsed once when the job is added or rescheduled
+func (job Job) schedule() (cron.Schedule, error) {
+	if job.options.dynamic != nil {
+		return nil, errDynamicPlan
+	}
+
+	if job.sc != nil {
+		return job.sc, nil
+	}
+
+	return job.parseSchedule()
+}
+
+// parseSchedule parse the plan of job with the parser of scheduler when the job is added
+func (job Job) parseSchedule() (cron.Schedule, error) {
+	parser := job.parser
+	if parser == nil {
+		parser = planParser
+	}
+
+	sc, err := parser.Parse(job.Plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return job.inLocation(sc), nil
+}
+
+// inLocation make the schedule use the location of job set by WithLocation, plans with an explicit
+// CRON_TZ= or TZ= prefix keep their own location
+func (job Job) inLocation(sc cron.Schedule) cron.Schedule {
+	spec, ok := sc.(*cron.SpecSchedule)
+	if !ok || job.options.location == nil || spec.Location != time.Local {
+		return sc
+	}
+
+	located := *spec
+	located.Location = job.options.location
+	return &located
+}
+
+func (c *schedulerImpl) SetCronMode(mode CronMode) {
+	c.parser = mode.parser()
+}
+
+func (c *schedulerImpl) SetParserOptions(options cron.ParseOption) {
+	c.parser = cron.NewParser(options)
+}
+
+// schedule parse the plan with the parser of scheduler, and add the job to cron
+//
+// Plans are always parsed by scheduler instead of the cron instance, so that the live cron
+// and Job.Next interpret plans in the same way
+func (c *schedulerImpl) schedule(job *Job, plan string) (cron.EntryID, error) {
+	if job.options.dynamic != nil {
+		return c.scheduleDynamic(job), nil
+	}
+
+	parser := c.parser
+	if job.Internal {
+		parser = planParser
+	}
+
+	sc, err := parser.Parse(plan)
+	if err != nil {
+		return 0, err
+	}
+
+	sc = job.inLocation(sc)
+	handler, run := job.handler, job.run
+	return c.cr.Schedule(sc, cron.FuncJob(func() {
+		// the scheduled time is captured before the execution is delayed by spread or jitter
+		scheduled := c.scheduledTime(job)
+		if !c.spread(job, scheduled) || !c.jitter(job) {
+			return
+		}
+
+		// internal jobs have no run wrapper
+		if run == nil {
+			handler()
+			return
+		}
+
+		run(&execution{scheduled: scheduled})
+	})), nil
+}
@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+	"github.com/pkg/errors"
+)
+
+// PauseUntil pause the job now, and continue it automatically at until
+//
+// The automatic resume is kept in memory only, it's lost when the process restarts unless a JobStore
+// is used (see RestoreFrom). Calling Pause or Continue before until cancels the automatic resume.
+func (c *schedulerImpl) PauseUntil(name string, until time.Time) error {
+	c.lock.Lock()
+	defer c.unlock()
+
+	return c.pauseJobUntil(name, until)
+}
+
+// pauseJobUntil pause the job until the time, the caller must hold the write lock
+func (c *schedulerImpl) pauseJobUntil(name string, until time.Time) error {
+	reg, err := c.userJob(name)
+	if err != nil {
+		return err
+	}
+
+	now := c.clock.Now()
+	if !until.After(now) {
+		return errors.Errorf("[glacier] can not pause job [%s] until %s, it's in the past", name, until.Format(time.RFC3339))
+	}
+
+	if !reg.Paused {
+		c.cr.Remove(reg.ID)
+		reg.Paused = true
+	}
+
+	reg.pausedByAll = false
+
+	c.cancelResume(reg)
+	reg.PausedUntil = until
+	reg.resumeTimer = time.AfterFunc(until.Sub(now), func() { c.serially(func() { c.resume(name, until) }) })
+
+	if infra.DEBUG {
+		log.WithFields(infra.Fields{"job": name, "until": until}).Debugf("[glacier] change job [%s] to paused until %s", name, until.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// PauseAll pause all jobs added by users under a single lock, internal jobs and the jobs already paused
+// are skipped, so that ContinueAll only continues the jobs paused here. A job failed to be paused doesn't
+// stop the others from being paused, the errors of jobs are aggregated
+func (c *schedulerImpl) PauseAll() error {
+	c.lock.Lock()
+	defer c.unlock()
+
+	problems := make([]string, 0)
+	for name, job := range c.jobs {
+		if job.Internal || job.Paused {
+			continue
+		}
+
+		if err := c.pauseJob(name); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		job.pausedByAll = true
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("[glacier] pause all jobs failed: %s", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+// ContinueAll continue all jobs paused by PauseAll under a single lock, the errors of jobs are aggregated
+func (c *schedulerImpl) ContinueAll() error {
+	c.lock.Lock()
+	defer c.unlock()
+
+	problems := make([]string, 0)
+	for name, job := range c.jobs {
+		if !job.Paused || !job.pausedByAll {
+			continue
+		}
+
+		if err := c.continueJob(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("[glacier] continue all jobs failed: %s", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+// resume continue the job paused by PauseUntil, it does nothing if the pause has been changed since then
+func (c *schedulerImpl) resume(name string, until time.Time) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	reg, exist := c.jobs[name]
+	if !exist || !reg.Paused || !reg.PausedUntil.Equal(until) {
+		return
+	}
+
+	if err := c.continueJob(name); err != nil {
+		log.WithFields(infra.Fields{"job": name, "error": err}).Errorf("[glacier] cron job [%s] can not be continued automatically: %v", name, err)
+	}
+}
+
+// cancelResume cancel the automatic resume of job, the caller must hold the write lock
+func (c *schedulerImpl) cancelResume(job *Job) {
+	if job.resumeTimer != nil {
+		job.resumeTimer.Stop()
+		job.resumeTimer = nil
+	}
+
+	job.PausedUntil = time.Time{}
+}
@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"context"
+	"time"
 
 	"github.com/mylxsw/glacier/log"
 
@@ -12,6 +13,7 @@ import (
 type provider struct {
 	creator func(cc infra.Resolver, creator JobCreator)
 	options []Option
+	serial  bool
 }
 
 func (p *provider) Priority() int {
@@ -22,13 +24,30 @@ func Provider(creator func(cc infra.Resolver, creator JobCreator), options ...Op
 	return &provider{creator: creator, options: options}
 }
 
+// SerialProvider 与 Provider 相同，但是调度器的所有操作都会通过单个 goroutine 串行执行，参考 NewSerialManager
+func SerialProvider(creator func(cc infra.Resolver, creator JobCreator), options ...Option) infra.DaemonProvider {
+	return &provider{creator: creator, options: options, serial: true}
+}
+
 func (p *provider) Register(app infra.Binder) {
 	// 定时任务对象
 	app.MustSingletonOverride(func() *cronV3.Cron {
 		return cronV3.New(cronV3.WithSeconds(), cronV3.WithLogger(cronLogger{}))
 	})
 	app.MustSingletonOverride(func(resolver infra.Resolver) Scheduler {
-		cr := NewManager(resolver)
+		var cr Scheduler
+		if p.serial {
+			cr = NewSerialManager(resolver)
+		} else {
+			cr = NewManager(resolver)
+		}
+
+		if labels := infra.DefaultLabels(resolver); len(labels) > 0 {
+			if err := cr.SetLabels(labels); err != nil {
+				panic(err)
+			}
+		}
+
 		for _, opt := range p.options {
 			opt(resolver, cr)
 		}
@@ -36,6 +55,8 @@ func (p *provider) Register(app infra.Binder) {
 		return cr
 	})
 	app.MustSingletonOverride(func(cr Scheduler) JobCreator { return cr })
+	// 兼容 *cron.Cron 的 AddFunc/AddJob/Remove 接口，通过它添加的任务由调度器管理，便于迁移直接使用 *cron.Cron 的代码
+	app.MustSingletonOverride(func(cr Scheduler) *CronAdapter { return NewCronAdapter(cr, "cron") })
 }
 
 func (p *provider) Boot(app infra.Resolver) {
@@ -70,3 +91,183 @@ func SetLockManagerOption(lockManager func(resolver infra.Resolver) LockManagerB
 		cr.LockManagerBuilder(lockManager(resolver))
 	}
 }
+
+// SetClockOption 设置调度器使用的时钟，一般用于测试
+func SetClockOption(clock Clock) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetClock(clock)
+	}
+}
+
+// SetRecorderOption 设置任务执行记录器，用于记录每一次任务的执行情况
+func SetRecorderOption(recorder func(resolver infra.Resolver) Recorder) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetRecorder(recorder(resolver))
+	}
+}
+
+// SetSpecRewriterOption 设置任务计划重写器，在任务添加之前对执行计划进行修改，返回错误则拒绝添加该任务
+func SetSpecRewriterOption(rewriter SpecRewriter) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetSpecRewriter(rewriter)
+	}
+}
+
+// SetWaitForLeadershipOption 启动时阻塞等待，直到所有任务都获取到分布式锁或者超时
+// 超时后，未获取到锁的任务以 follower 身份运行：每次调度时依然会尝试获取锁，获取成功后才会执行
+func SetWaitForLeadershipOption(timeout time.Duration) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetWaitForLeadership(timeout)
+	}
+}
+
+// SetOnReadyLockTimeoutOption 通过 AddAndRunOnServerReady 添加的任务，在服务启动时执行前最多等待 timeout 时间获取分布式锁
+// 超时未获取到锁时放弃本次启动时的执行，之后获取到锁时按照正常的执行计划执行
+func SetOnReadyLockTimeoutOption(timeout time.Duration) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetOnReadyLockTimeout(timeout)
+	}
+}
+
+// SetOnLockLostOption 设置任务的分布式锁丢失（获取锁之后 TryLock 失败）时的回调函数，回调之前该任务正在执行的 context 会被取消，
+// 同时会发布 JobLockLostEvent 事件，获取到锁时发布 JobLockAcquiredEvent 事件
+func SetOnLockLostOption(handler func(resolver infra.Resolver, name string)) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.OnLockLost(func(name string) { handler(resolver, name) })
+	}
+}
+
+// SetLockRefreshIntervalOption 设置刷新任务已持有的分布式锁的时间间隔，默认为 60s，小于等于 0 时使用默认值
+// 该间隔应该小于分布式锁的过期时间（建议为过期时间的一半），否则锁可能在两次刷新之间过期，导致任务被其它节点接管
+func SetLockRefreshIntervalOption(interval time.Duration) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetLockRefreshInterval(interval)
+	}
+}
+
+// SetHeartbeatOption 开启调度器心跳，每隔 interval 更新一次 LastHeartbeat，publishEvent 为 true 时同时发布 HeartbeatEvent 事件
+// interval 小于等于 0 时使用默认值 30s
+func SetHeartbeatOption(interval time.Duration, publishEvent bool) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetHeartbeat(interval, publishEvent)
+	}
+}
+
+// SetCronModeOption 设置任务执行计划的解析模式，默认为 CronWithSeconds（6 位，包含秒），CronStandard 为标准的 5 位 crontab 格式
+// 执行计划由调度器统一解析，Job.Next 与实际调度使用相同的解析规则
+func SetCronModeOption(mode CronMode) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetCronMode(mode)
+	}
+}
+
+// SetParserOptionsOption 设置解析任务执行计划时使用的字段，比如 cron.SecondOptional | cron.Minute | ... 可以让秒字段变为可选，
+// 会覆盖 SetCronModeOption 的设置，Job.Next 与实际调度使用相同的解析规则
+// 注意：options 中不包含 cron.Descriptor 时，任务的执行计划不能使用 @every 10s 等描述符，调度器内部任务（心跳，分布式锁刷新）不受影响
+func SetParserOptionsOption(options cronV3.ParseOption) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetParserOptions(options)
+	}
+}
+
+// SetTracerOption 设置任务执行的 Tracer，每次执行（包括重试）都会创建一个 span，可以用于接入 OpenTelemetry 等链路追踪系统
+func SetTracerOption(tracer Tracer) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetTracer(tracer)
+	}
+}
+
+// SetExecutorPoolSizeOption 限制所有任务的最大并发执行数量，超出时等待其它任务执行完毕，运行时可以通过 Scheduler.SetExecutorPoolSize 调整
+func SetExecutorPoolSizeOption(n int) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetExecutorPoolSize(n)
+	}
+}
+
+// SetMaxConcurrencyOption 限制所有任务的最大并发执行数量（与 SetExecutorPoolSizeOption 共用执行器池），超出时最多等待 maxWait 时间，
+// 仍然没有空闲的执行器时跳过本次执行，maxWait 为 0 时直接跳过，小于 0 时一直等待（与 SetExecutorPoolSizeOption 相同）
+func SetMaxConcurrencyOption(n int, maxWait time.Duration) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetMaxConcurrencyWait(maxWait)
+		cr.SetMaxConcurrency(n)
+	}
+}
+
+// SetTickSpreadOption 同一时刻触发的多个任务，按照注册顺序在 window 时间窗口内依次错开执行，避免同时访问共享资源
+func SetTickSpreadOption(window time.Duration) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetTickSpread(window)
+	}
+}
+
+// SetRunScopeOption 为任务的每一次执行创建独立的 RunScope，任务函数可以通过 *RunScope 参数注册资源清理函数，执行完毕后自动清理
+// limit 为同时持有 RunScope 的最大执行数量，超出时等待其它执行释放，小于等于 0 时不限制
+func SetRunScopeOption(limit int) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetRunScope(limit)
+	}
+}
+
+// SetPanicHandlerOption 设置任务 panic 时的处理函数，处理函数可以获取到任务信息以及原始的堆栈信息，用于上报到 Sentry 等错误追踪系统
+// 设置处理函数后，默认的错误日志依然会输出；同时设置了 SetPanicFormatterOption 时，总是先调用处理函数，再由错误转换器生成本次执行的错误
+func SetPanicHandlerOption(handler PanicHandler) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetPanicHandler(handler)
+	}
+}
+
+// SetPanicFormatterOption 设置任务 panic 时的错误转换器，返回的错误将作为本次执行的结果记录
+// 错误转换器在 SetPanicHandlerOption 设置的处理函数之后调用，上报错误追踪系统请使用处理函数，避免重复上报
+func SetPanicFormatterOption(formatter PanicFormatter) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetPanicFormatter(formatter)
+	}
+}
+
+// SetDrainOption 停止调度器时最多等待 timeout 时间，等待正在执行的任务执行完毕，超时后的行为由 policy 决定
+// DetachOnTimeout 将不再等待，未完成的任务会被记录到日志中，并在进程退出时被终止；WaitOnTimeout 将继续等待
+// 默认（timeout 小于等于 0）会一直等待所有正在执行的任务执行完毕
+func SetDrainOption(timeout time.Duration, policy DrainPolicy) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetDrain(timeout, policy)
+	}
+}
+
+// SetMiddlewaresOption 注册任务中间件，中间件会包裹所有任务的每一次执行，先注册的中间件在最外层
+func SetMiddlewaresOption(middlewares ...Middleware) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.Use(middlewares...)
+	}
+}
+
+// SetEventPublishingOption 启用任务执行事件，每次任务执行时通过事件管理器发布 JobStartedEvent，以及 JobCompletedEvent 或 JobFailedEvent
+func SetEventPublishingOption(enabled bool) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.EnableEventPublishing(enabled)
+	}
+}
+
+// SetDefaultsOption 设置所有任务的默认选项，例如默认的超时时间，添加任务时指定的选项会覆盖默认选项
+func SetDefaultsOption(options ...JobOption) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetDefaults(options...)
+	}
+}
+
+// SetLabelsOption 设置调度器的标签，标签会添加到所有的监控指标和 Webhook 中
+// 默认使用容器中的 infra.Environment（env）和 infra.ServiceName（service）作为标签
+// 标签名必须是合法的 OpenMetrics 标签名，并且不能是 job 和 le，否则会 panic
+func SetLabelsOption(labels map[string]string) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		if err := cr.SetLabels(labels); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// SetTagConcurrencyOption 限制包含指定标签的任务的最大并发执行数量，达到上限时本次调度将会被跳过
+func SetTagConcurrencyOption(tag string, limit int) Option {
+	return func(resolver infra.Resolver, cr Scheduler) {
+		cr.SetTagConcurrency(tag, limit)
+	}
+}
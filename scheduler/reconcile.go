@@ -0,0 +1,149 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+	"github.com/pkg/errors"
+)
+
+// JobConfig is the desired state of a job used by Reconcile
+type JobConfig struct {
+	Name    string
+	Plan    string
+	Handler interface{}
+	Options []JobOption
+}
+
+// ReconcileResult is the changes applied by Reconcile
+type ReconcileResult struct {
+	Added       []string `json:"added"`
+	Removed     []string `json:"removed"`
+	Rescheduled []string `json:"rescheduled"`
+}
+
+// Reconcile compare the desired jobs with the registered jobs, and apply the minimal changes
+//   - jobs not registered will be added
+//   - registered jobs not in desired will be removed
+//   - registered jobs with a different plan will be rescheduled, the paused state is kept
+//
+// Jobs with the same plan are untouched, their handler and options will not be replaced.
+// Registered jobs without a cron spec (dynamic jobs and one-shot jobs added by RunAt) have no plan to
+// compare, they are untouched when they are desired, and they are kept when they are not.
+// All desired jobs are validated (and the new ones are built) before any change is applied, so an
+// invalid config leaves the scheduler untouched. All changes are applied under the scheduler lock,
+// so other goroutines never see a half reconciled scheduler.
+func (c *schedulerImpl) Reconcile(desired []JobConfig) (ReconcileResult, error) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	result := ReconcileResult{Added: []string{}, Removed: []string{}, Rescheduled: []string{}}
+
+	plans := make(map[string]string)
+	added := make([]*Job, 0)
+	for _, conf := range desired {
+		if _, ok := plans[conf.Name]; ok {
+			return result, fmt.Errorf("[glacier] reconcile failed: job [%s] is duplicated", conf.Name)
+		}
+
+		reg, ok := c.jobs[conf.Name]
+		if !ok {
+			job, err := c.newJob(conf.Name, conf.Plan, conf.Handler, conf.Options...)
+			if err != nil {
+				return result, errors.Wrapf(err, "[glacier] reconcile failed: invalid job [%s]", conf.Name)
+			}
+
+			added = append(added, job)
+			plans[conf.Name] = job.Plan
+			continue
+		}
+
+		if err := validateHandler(conf.Handler); err != nil {
+			return result, errors.Wrapf(err, "[glacier] reconcile failed: invalid handler for job [%s]", conf.Name)
+		}
+
+		if reg.options.dynamic != nil {
+			plans[conf.Name] = reg.Plan
+			continue
+		}
+
+		plan, err := c.rewritePlan(conf.Name, conf.Plan)
+		if err != nil {
+			return result, err
+		}
+
+		if _, err := c.parser.Parse(plan); err != nil {
+			return result, errors.Wrapf(err, "[glacier] reconcile failed: invalid plan for job [%s]", conf.Name)
+		}
+
+		plans[conf.Name] = plan
+	}
+
+	// the prerequisites of new jobs must be in the desired jobs, since the others are removed
+	for _, job := range added {
+		if err := checkRunAfter(job, func(name string) bool { _, ok := plans[name]; return ok }); err != nil {
+			return result, errors.Wrap(err, "[glacier] reconcile failed")
+		}
+	}
+
+	for name, job := range c.jobs {
+		if _, ok := plans[name]; ok || job.Internal || job.options.dynamic != nil {
+			continue
+		}
+
+		if err := c.removeJob(name); err != nil {
+			return result, err
+		}
+
+		result.Removed = append(result.Removed, name)
+	}
+
+	for _, conf := range desired {
+		job, ok := c.jobs[conf.Name]
+		if !ok || job.Plan == plans[conf.Name] {
+			continue
+		}
+
+		if err := c.reschedule(job, plans[conf.Name]); err != nil {
+			return result, err
+		}
+
+		result.Rescheduled = append(result.Rescheduled, conf.Name)
+	}
+
+	for _, job := range added {
+		if err := c.registerJob(job); err != nil {
+			return result, err
+		}
+
+		result.Added = append(result.Added, job.Name)
+	}
+
+	return result, nil
+}
+
+// reschedule change the plan of job, the caller must hold the write lock
+func (c *schedulerImpl) reschedule(job *Job, plan string) error {
+	if !job.Paused {
+		id, err := c.schedule(job, plan)
+		if err != nil {
+			return errors.Wrapf(err, "[glacier] reschedule job [%s] failed", job.Name)
+		}
+
+		c.cr.Remove(job.ID)
+		job.ID = id
+	}
+
+	job.Plan = plan
+	job.sc = nil
+	if job.options.dynamic == nil {
+		job.sc, _ = job.parseSchedule()
+	}
+
+	if infra.DEBUG {
+		log.WithFields(infra.Fields{"job": job.Name, "plan": plan}).Debugf("[glacier] job [%s] rescheduled to %s", job.Name, plan)
+	}
+
+	return nil
+}
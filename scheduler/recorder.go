@@ -0,0 +1,228 @@
+package scheduler
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+	"github.com/pkg/errors"
+)
+
+// ScheduleRecord is a record for one execution of a job
+type ScheduleRecord struct {
+	Name string `json:"name"`
+	// ScheduledTime is the time the execution was planned for by cron, it's captured before the delays
+	// like jitter, for manual executions (like Trigger) it's the actual start time
+	ScheduledTime time.Time     `json:"scheduled_time"`
+	ActualStart   time.Time     `json:"actual_start"`
+	Duration      time.Duration `json:"duration"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// Recorder records the executions of jobs, it is useful for reproducing scheduling problems
+type Recorder interface {
+	Record(rec ScheduleRecord) error
+}
+
+// DefaultHistoryLimit is the max number of records returned by History when no limit is given
+const DefaultHistoryLimit = 100
+
+// HistoryRecorder is a Recorder which can query the saved records, it's required by Scheduler.History
+type HistoryRecorder interface {
+	Recorder
+	// History get the latest records of job, newest first, at most limit records are returned
+	History(name string, limit int) ([]ScheduleRecord, error)
+}
+
+// MemoryRecorder is a HistoryRecorder which keeps the latest records of each job in memory
+type MemoryRecorder struct {
+	lock    sync.RWMutex
+	size    int
+	records map[string][]ScheduleRecord
+}
+
+// NewMemoryRecorder create a MemoryRecorder which keeps at most size records for each job,
+// size <= 0 means DefaultHistoryLimit
+func NewMemoryRecorder(size int) *MemoryRecorder {
+	if size <= 0 {
+		size = DefaultHistoryLimit
+	}
+
+	return &MemoryRecorder{size: size, records: make(map[string][]ScheduleRecord)}
+}
+
+func (r *MemoryRecorder) Record(rec ScheduleRecord) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	records := append(r.records[rec.Name], rec)
+	if len(records) > r.size {
+		records = records[len(records)-r.size:]
+	}
+
+	r.records[rec.Name] = records
+	return nil
+}
+
+func (r *MemoryRecorder) History(name string, limit int) ([]ScheduleRecord, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	records := r.records[name]
+	if limit <= 0 || limit > len(records) {
+		limit = len(records)
+	}
+
+	history := make([]ScheduleRecord, 0, limit)
+	for i := len(records) - 1; i >= len(records)-limit; i-- {
+		history = append(history, records[i])
+	}
+
+	return history, nil
+}
+
+// FileRecorder is a Recorder which writes records to a file, one json object per line
+type FileRecorder struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+// NewFileRecorder create a FileRecorder, records will be appended to the file
+func NewFileRecorder(path string) (*FileRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "[glacier] open record file failed")
+	}
+
+	return &FileRecorder{file: file}, nil
+}
+
+func (r *FileRecorder) Record(rec ScheduleRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_, err = r.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close the underlying file
+func (r *FileRecorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.file.Close()
+}
+
+// LoadRecords load records from a file written by FileRecorder
+func LoadRecords(path string) ([]ScheduleRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "[glacier] open record file failed")
+	}
+	defer file.Close()
+
+	records := make([]ScheduleRecord, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec ScheduleRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, errors.Wrap(err, "[glacier] invalid schedule record")
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, scanner.Err()
+}
+
+// Replay executes the jobs in records one by one synchronously, ordered by scheduled time.
+// Before each execution, the clock will be set to the actual start time of the record,
+// so the scheduler must be configured with the same clock by SetClock.
+func Replay(s Scheduler, clock *FakeClock, records []ScheduleRecord) error {
+	runner, ok := s.(interface{ runJob(name string) error })
+	if !ok {
+		return errors.New("[glacier] scheduler does not support replay")
+	}
+
+	sorted := make([]ScheduleRecord, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ScheduledTime.Before(sorted[j].ScheduledTime) })
+
+	for _, rec := range sorted {
+		clock.Set(rec.ActualStart)
+		if err := runner.runJob(rec.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *schedulerImpl) record(name string, scheduled time.Time, startTs time.Time, err error) {
+	if c.recorder == nil {
+		return
+	}
+
+	if scheduled.IsZero() {
+		scheduled = startTs
+	}
+
+	rec := ScheduleRecord{
+		Name:          name,
+		ScheduledTime: scheduled,
+		ActualStart:   startTs,
+		Duration:      c.clock.Now().Sub(startTs),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	if err := c.recorder.Record(rec); err != nil {
+		log.WithFields(infra.Fields{"job": name, "error": err}).Errorf("[glacier] record cron job [%s] failed: %v", name, err)
+	}
+}
+
+func (c *schedulerImpl) History(name string, limit int) ([]ScheduleRecord, error) {
+	if _, err := c.Info(name); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+
+	recorder, ok := c.recorder.(HistoryRecorder)
+	if !ok {
+		return nil, errors.Wrapf(ErrHistoryNotSupported, "[glacier] history of job [%s]", name)
+	}
+
+	return recorder.History(name, limit)
+}
+
+// runJob execute the job synchronously
+func (c *schedulerImpl) runJob(name string) error {
+	c.lock.RLock()
+	job, ok := c.jobs[name]
+	c.lock.RUnlock()
+
+	if !ok {
+		return jobNotFoundError(name)
+	}
+
+	job.handler()
+	return nil
+}
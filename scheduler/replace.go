@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+	"github.com/pkg/errors"
+)
+
+// AddOrReplace add a cron job, if a job with the same name exists, it's replaced by the new one under the
+// scheduler lock, so there is no window that the name is unregistered. The paused state (including
+// PausedUntil) of the replaced job is kept, and the statistics start over. When the new job can not be
+// added, the existing job is untouched.
+func (c *schedulerImpl) AddOrReplace(name string, plan string, handler interface{}, options ...JobOption) error {
+	c.lock.Lock()
+	defer c.unlock()
+
+	old, existed := c.jobs[name]
+	if !existed {
+		_, err := c.addJob(name, plan, handler, options...)
+		return err
+	}
+
+	if old.Internal {
+		return errors.Wrapf(ErrInternalJob, "[glacier] job with name [%s]", name)
+	}
+
+	delete(c.jobs, name)
+	if _, err := c.addJob(name, plan, handler, options...); err != nil {
+		c.jobs[name] = old
+		return err
+	}
+
+	job := c.jobs[name]
+	c.replaceJob(old, job)
+
+	if infra.DEBUG {
+		log.WithFields(infra.Fields{"job": name, "plan": job.Plan}).Debugf("[glacier] job [%s] replaced (%s -> %s)", name, old.Plan, job.Plan)
+	}
+
+	return nil
+}
+
+// replaceJob remove the entry of old job from cron, and move its paused state and distributed lock
+// to the new job, the caller must hold the write lock
+func (c *schedulerImpl) replaceJob(old *Job, job *Job) {
+	if !old.Paused {
+		c.cr.Remove(old.ID)
+	}
+
+	// the lock held by old job is kept, so the leadership of job is not lost during replacement
+	if old.lockManager != nil {
+		if job.lockManager != nil {
+			job.lockManager = old.lockManager
+		} else {
+			c.locks.forget(job.Name)
+			if err := old.lockManager.Release(context.TODO()); err != nil {
+				log.WithFields(infra.Fields{"job": job.Name, "error": err}).Errorf("[glacier] cron job [%s] can not release lock", job.Name)
+			}
+		}
+	}
+
+	if !old.Paused {
+		return
+	}
+
+	c.cr.Remove(job.ID)
+	job.Paused, job.pausedByAll = true, old.pausedByAll
+	job.PausedUntil, job.resumeTimer = old.PausedUntil, old.resumeTimer
+}
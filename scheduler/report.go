@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// ShutdownReport is the final state of scheduler when it's stopped
+type ShutdownReport struct {
+	TotalRuns     int64 `json:"total_runs"`
+	TotalFailures int64 `json:"total_failures"`
+	// Running is the jobs which still have executions in progress, these executions may be cut short
+	Running []string `json:"running"`
+	// Paused is the jobs still paused
+	Paused []string `json:"paused"`
+}
+
+func (r ShutdownReport) String() string {
+	return fmt.Sprintf(
+		"total runs: %d, total failures: %d, running: [%s], paused: [%s]",
+		r.TotalRuns,
+		r.TotalFailures,
+		strings.Join(r.Running, ", "),
+		strings.Join(r.Paused, ", "),
+	)
+}
+
+// shutdownReport create a report for current state of all user jobs
+func (c *schedulerImpl) shutdownReport() ShutdownReport {
+	report := ShutdownReport{Running: []string{}, Paused: []string{}}
+	for _, job := range c.List() {
+		report.TotalRuns += job.Stats.RunCount
+		report.TotalFailures += job.Stats.FailureCount
+
+		if job.Stats.Running > 0 {
+			report.Running = append(report.Running, job.Name)
+		}
+
+		if job.Paused {
+			report.Paused = append(report.Paused, job.Name)
+		}
+	}
+
+	return report
+}
+
+// reportShutdown write the shutdown report to log, and publish a SchedulerStoppedEvent
+func (c *schedulerImpl) reportShutdown() {
+	report := c.shutdownReport()
+	log.Infof("[glacier] scheduler stopped, %s", report)
+
+	c.publish(SchedulerStoppedEvent{Report: report, Time: c.clock.Now()})
+}
+
+// reportStartup write the summary of jobs to log, and publish a SchedulerStartedEvent
+func (c *schedulerImpl) reportStartup() {
+	evt := SchedulerStartedEvent{HasLock: len(c.locks.holding()) > 0, Time: c.clock.Now()}
+	for _, job := range c.List() {
+		evt.JobCount++
+		if job.Paused {
+			evt.PausedCount++
+		}
+	}
+
+	log.WithFields(infra.Fields{
+		"jobs":     evt.JobCount,
+		"paused":   evt.PausedCount,
+		"has_lock": evt.HasLock,
+	}).Infof("[glacier] scheduler started, %d jobs registered, %d paused", evt.JobCount, evt.PausedCount)
+
+	c.publish(evt)
+}
@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// runWithRetry call the handler of job, and retry it with exponential backoff when it returns an error,
+// see WithRetry. All attempts share the run context, so they share the deadline of WithTimeout, and no
+// more attempt is made once the context is done (the scheduler is stopped or the lock of job is lost).
+// The result of the last attempt is returned
+func (c *schedulerImpl) runWithRetry(job *Job, hh JobHandler, runID string, exec *execution) (interface{}, error) {
+	var deadline time.Time
+	if job.options.timeout > 0 {
+		deadline = c.clock.Now().Add(job.options.timeout)
+	}
+
+	ctx, cancel := c.runContext(job, runID, job.options.timeout)
+	defer cancel()
+
+	result, err := c.resolveHandler(ctx, job, hh, exec)
+
+	backoff := job.options.retryBackoff
+	for attempt := 1; err != nil && attempt <= job.options.maxRetries; attempt++ {
+		if ctx.Err() != nil {
+			if infra.WARN {
+				log.WithFields(infra.Fields{"job": job.Name, "run_id": runID, "error": err}).Warningf("[glacier] cron job [%s] failed, no retry because the run is cancelled", job.Name)
+			}
+
+			break
+		}
+
+		if !deadline.IsZero() && c.clock.Now().Add(backoff).After(deadline) {
+			if infra.WARN {
+				log.WithFields(infra.Fields{"job": job.Name, "run_id": runID, "error": err, "attempt": attempt, "max_retries": job.options.maxRetries}).Warningf("[glacier] cron job [%s] failed, no time left for retry", job.Name)
+			}
+
+			break
+		}
+
+		if infra.WARN {
+			log.WithFields(infra.Fields{"job": job.Name, "run_id": runID, "error": err, "attempt": attempt, "max_retries": job.options.maxRetries, "backoff": backoff}).Warningf("[glacier] cron job [%s] failed, retry after %s", job.Name, backoff)
+		}
+
+		select {
+		case <-c.after(backoff):
+		case <-ctx.Done():
+			continue
+		}
+
+		result, err = c.resolveHandler(ctx, job, hh, exec)
+		backoff *= 2
+	}
+
+	return result, err
+}
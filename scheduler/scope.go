@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+	"github.com/mylxsw/go-ioc"
+)
+
+// RunScope is the scope of a single execution of job, it's created for every run when SetRunScope is enabled.
+// Handlers can get it by adding a *RunScope argument, and register cleanup functions for the resources
+// they acquire, these functions are called in reverse order after the handler returns.
+type RunScope struct {
+	lock     sync.Mutex
+	name     string
+	cleanups []func() error
+}
+
+// Name return the name of the job which the scope belongs to
+func (scope *RunScope) Name() string {
+	return scope.name
+}
+
+// Defer register a cleanup function which is called after the handler returns
+func (scope *RunScope) Defer(cleanup func() error) {
+	scope.lock.Lock()
+	defer scope.lock.Unlock()
+
+	scope.cleanups = append(scope.cleanups, cleanup)
+}
+
+// release call all cleanup functions in reverse order
+func (scope *RunScope) release() {
+	scope.lock.Lock()
+	cleanups := scope.cleanups
+	scope.cleanups = nil
+	scope.lock.Unlock()
+
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		if err := cleanups[i](); err != nil {
+			log.WithFields(infra.Fields{"job": scope.name, "error": err}).Errorf("[glacier] cron job [%s] cleanup failed: %v", scope.name, err)
+		}
+	}
+}
+
+// SetRunScope enable the per-run scope, limit is the max number of runs holding a scope at the same time,
+// runs exceeding the limit wait until a scope is released. limit <= 0 means no limit.
+func (c *schedulerImpl) SetRunScope(limit int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.runScopeEnabled = true
+	c.runScopeSemaphore = nil
+	if limit > 0 {
+		c.runScopeSemaphore = make(chan struct{}, limit)
+	}
+}
+
+// resolveHandler call the handler of job with the run context, within a child container providing the
+// context, and the run scope if it's enabled, and return the result of handler (see ResultJobHandler).
+// The panic of handler is returned as an error, so the attempt is traced by the tracer with its real result.
+// The run scope is released after the handler returns, even if it's after timeout
+func (c *schedulerImpl) resolveHandler(ctx context.Context, job *Job, hh JobHandler, exec *execution) (result interface{}, err error) {
+	c.lock.RLock()
+	enabled, semaphore := c.runScopeEnabled, c.runScopeSemaphore
+	c.lock.RUnlock()
+
+	ctx, finish := c.startSpan(ctx, job)
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result, err = nil, c.recoverPanic(job, recovered, debug.Stack())
+		}
+
+		finish(err)
+	}()
+
+	var scope *RunScope
+	cleanup := func() {}
+	if enabled {
+		if semaphore != nil {
+			semaphore <- struct{}{}
+		}
+
+		scope = &RunScope{name: job.Name}
+		cleanup = func() {
+			scope.release()
+			if semaphore != nil {
+				<-semaphore
+			}
+		}
+	}
+
+	resolver := c.runResolver(job.Name, ctx, scope)
+	if _, ok := ctx.Deadline(); !ok {
+		defer cleanup()
+		return handle(hh, resolver)
+	}
+
+	return c.handleWithTimeout(ctx, job, hh, resolver, cleanup, exec)
+}
+
+// handleWithTimeout call the handler in a new goroutine, and stop waiting for it when ctx is done.
+// The handler keeps running after timeout, it should return as soon as possible when ctx is cancelled,
+// cleanup is called when it returns, and the local guards of exec are held until then
+func (c *schedulerImpl) handleWithTimeout(ctx context.Context, job *Job, hh JobHandler, resolver infra.Resolver, cleanup func(), exec *execution) (interface{}, error) {
+	type handleResult struct {
+		result interface{}
+		err    error
+	}
+
+	done := make(chan handleResult, 1)
+	exec.handlers.Add(1)
+	go func() {
+		defer exec.handlers.Done()
+
+		var res handleResult
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					res = handleResult{err: c.recoverPanic(job, err, debug.Stack())}
+				}
+			}()
+
+			res.result, res.err = handle(hh, resolver)
+		}()
+
+		// the scope is released before the result is reported, the same as the handler without timeout
+		cleanup()
+		done <- res
+	}()
+
+	select {
+	case res := <-done:
+		return res.result, res.err
+	case <-ctx.Done():
+		exec.detached = true
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("[glacier] cron job [%s] timeout after %s: %w", job.Name, job.options.timeout, ctx.Err())
+		}
+
+		return nil, fmt.Errorf("[glacier] cron job [%s] is interrupted: %w", job.Name, ctx.Err())
+	}
+}
+
+// runResolver create a child container of resolver which provides the run context, and the scope if it's not nil
+func (c *schedulerImpl) runResolver(name string, ctx context.Context, scope *RunScope) infra.Resolver {
+	parent, ok := c.resolver.(ioc.Container)
+	if !ok {
+		c.resolverWarning.Do(func() {
+			log.Warningf("[glacier] resolver does not support child scope, run scope and context for jobs are not available")
+		})
+		return c.resolver
+	}
+
+	child := ioc.Extend(parent)
+	child.MustSingleton(func() context.Context { return ctx })
+	if scope != nil {
+		child.MustSingleton(func() *RunScope { return scope })
+	}
+
+	return child
+}
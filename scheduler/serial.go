@@ -0,0 +1,482 @@
+package scheduler
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	cron "github.com/robfig/cron/v3"
+)
+
+// serialScheduler is a Scheduler which executes all operations one by one in a single goroutine
+type serialScheduler struct {
+	scheduler *schedulerImpl
+	commands  chan func()
+
+	// lock protects running, and serializes the sends to commands
+	lock    sync.Mutex
+	running bool
+	// loopID is the id of the command goroutine, it's used to detect the reentrant calls from commands
+	loopID atomic.Int64
+}
+
+// NewSerialManager create a new Scheduler, all the operations (Add/Remove/Pause/Continue/Info...) of it are
+// sent to a command channel and executed one by one by a single goroutine. It trades a bit of latency for
+// simpler reasoning about concurrency.
+//
+// Jobs are still executed in their own goroutines, and job handlers can call the scheduler safely, but
+// the operations issued from job handlers wait in the same queue as others. The operations issued by
+// commands themselves (like the handlers called synchronously by a command) are executed inline.
+// The changes made by scheduler itself (automatic resume of PauseUntil, removal of one-shot jobs, internal
+// tasks like lock refresh) are sent to the same queue. Reads (Info, List...) are served by the snapshot
+// of jobs directly, they never wait in the queue.
+//
+// The command goroutine is started by the first operation, and exits when the scheduler is stopped, it's
+// started again if the scheduler is used after Stop.
+func NewSerialManager(resolver infra.Resolver) Scheduler {
+	s := &serialScheduler{
+		scheduler: NewManager(resolver).(*schedulerImpl),
+		commands:  make(chan func()),
+	}
+	s.scheduler.serial = s.internal
+
+	return s
+}
+
+// loop execute the commands one by one, until a nil command is received
+func (s *serialScheduler) loop() {
+	s.loopID.Store(goroutineID())
+	defer s.loopID.Store(0)
+
+	for cmd := range s.commands {
+		if cmd == nil {
+			return
+		}
+
+		cmd()
+	}
+}
+
+// do send fn to the command goroutine and wait for it to finish, panics in fn are passed to the caller.
+// The command goroutine is started if it's not running
+func (s *serialScheduler) do(fn func()) {
+	s.send(fn, true)
+}
+
+// internal send the changes made by scheduler itself to the command goroutine, they are executed in the
+// caller goroutine if the command goroutine is not running, so it's never started again after Stop by them
+func (s *serialScheduler) internal(fn func()) {
+	s.send(fn, false)
+}
+
+// send fn to the command goroutine and wait for it to finish, fn is executed inline when it's called from
+// the command goroutine, since the goroutine can not receive a command while executing another one
+func (s *serialScheduler) send(fn func(), start bool) {
+	if s.loopID.Load() == goroutineID() {
+		fn()
+		return
+	}
+
+	var panicErr interface{}
+	done := make(chan struct{})
+
+	s.lock.Lock()
+	if !s.running {
+		if !start {
+			s.lock.Unlock()
+			fn()
+			return
+		}
+
+		s.running = true
+		go s.loop()
+	}
+
+	s.commands <- func() {
+		defer func() {
+			panicErr = recover()
+			close(done)
+		}()
+
+		fn()
+	}
+	s.lock.Unlock()
+
+	<-done
+	if panicErr != nil {
+		panic(panicErr)
+	}
+}
+
+// goroutineID return the id of current goroutine, which is parsed from the stack header "goroutine 123 [running]:"
+func goroutineID() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	fields := bytes.Fields(buf)
+	if len(fields) < 2 {
+		return -1
+	}
+
+	id, err := strconv.ParseInt(string(fields[1]), 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return id
+}
+
+func (s *serialScheduler) Add(name string, plan string, handler interface{}, options ...JobOption) (err error) {
+	s.do(func() { err = s.scheduler.Add(name, plan, handler, options...) })
+	return
+}
+
+func (s *serialScheduler) AddOrReplace(name string, plan string, handler interface{}, options ...JobOption) (err error) {
+	s.do(func() { err = s.scheduler.AddOrReplace(name, plan, handler, options...) })
+	return
+}
+
+func (s *serialScheduler) AddAndReturnID(name string, plan string, handler interface{}, options ...JobOption) (id cron.EntryID, err error) {
+	s.do(func() { id, err = s.scheduler.AddAndReturnID(name, plan, handler, options...) })
+	return
+}
+
+func (s *serialScheduler) AddAndRunOnServerReady(name string, plan string, handler interface{}, options ...JobOption) (err error) {
+	s.do(func() { err = s.scheduler.AddAndRunOnServerReady(name, plan, handler, options...) })
+	return
+}
+
+func (s *serialScheduler) MustAdd(name string, plan string, handler interface{}, options ...JobOption) {
+	s.do(func() { s.scheduler.MustAdd(name, plan, handler, options...) })
+}
+
+func (s *serialScheduler) MustAddAndRunOnServerReady(name string, plan string, handler interface{}, options ...JobOption) {
+	s.do(func() { s.scheduler.MustAddAndRunOnServerReady(name, plan, handler, options...) })
+}
+
+func (s *serialScheduler) AddDynamic(name string, next func(last time.Time) (time.Time, error), handler interface{}, options ...JobOption) (err error) {
+	s.do(func() { err = s.scheduler.AddDynamic(name, next, handler, options...) })
+	return
+}
+
+func (s *serialScheduler) RunAt(name string, t time.Time, handler interface{}, options ...JobOption) (err error) {
+	s.do(func() { err = s.scheduler.RunAt(name, t, handler, options...) })
+	return
+}
+
+func (s *serialScheduler) RegisterStruct(v interface{}) error {
+	return registerStruct(s, v)
+}
+
+func (s *serialScheduler) Namespace(namespace string) JobCreator {
+	return &namespacedCreator{namespace: namespace, creator: s}
+}
+
+func (s *serialScheduler) AddAuto(plan string, handler interface{}, options ...JobOption) (name string, err error) {
+	s.do(func() { name, err = s.scheduler.AddAuto(plan, handler, options...) })
+	return
+}
+
+func (s *serialScheduler) SetNameGenerator(generator func() string) {
+	s.do(func() { s.scheduler.SetNameGenerator(generator) })
+}
+
+func (s *serialScheduler) Remove(name string) (err error) {
+	s.do(func() { err = s.scheduler.Remove(name) })
+	return
+}
+
+func (s *serialScheduler) Pause(name string) (err error) {
+	s.do(func() { err = s.scheduler.Pause(name) })
+	return
+}
+
+func (s *serialScheduler) Continue(name string) (err error) {
+	s.do(func() { err = s.scheduler.Continue(name) })
+	return
+}
+
+func (s *serialScheduler) PauseUntil(name string, until time.Time) (err error) {
+	s.do(func() { err = s.scheduler.PauseUntil(name, until) })
+	return
+}
+
+// ListByTag is served by the snapshot, the same as the other reads
+func (s *serialScheduler) ListByTag(tag string) []Job {
+	return s.scheduler.ListByTag(tag)
+}
+
+func (s *serialScheduler) PauseByTag(tag string) (err error) {
+	s.do(func() { err = s.scheduler.PauseByTag(tag) })
+	return
+}
+
+func (s *serialScheduler) ContinueByTag(tag string) (err error) {
+	s.do(func() { err = s.scheduler.ContinueByTag(tag) })
+	return
+}
+
+func (s *serialScheduler) RestoreFrom(store JobStore) (err error) {
+	s.do(func() { err = s.scheduler.RestoreFrom(store) })
+	return
+}
+
+func (s *serialScheduler) PauseAll() (err error) {
+	s.do(func() { err = s.scheduler.PauseAll() })
+	return
+}
+
+func (s *serialScheduler) ContinueAll() (err error) {
+	s.do(func() { err = s.scheduler.ContinueAll() })
+	return
+}
+
+func (s *serialScheduler) UpdatePlan(name string, plan string) (err error) {
+	s.do(func() { err = s.scheduler.UpdatePlan(name, plan) })
+	return
+}
+
+func (s *serialScheduler) Trigger(name string) (err error) {
+	s.do(func() { err = s.scheduler.Trigger(name) })
+	return
+}
+
+func (s *serialScheduler) MustTrigger(name string) {
+	s.do(func() { s.scheduler.MustTrigger(name) })
+}
+
+// Info is served by the snapshot of jobs, it's not sent to the command goroutine
+func (s *serialScheduler) Info(name string) (Job, error) {
+	return s.scheduler.Info(name)
+}
+
+func (s *serialScheduler) InfoByID(id cron.EntryID) (Job, error) {
+	return s.scheduler.InfoByID(id)
+}
+
+func (s *serialScheduler) List() []Job {
+	return s.scheduler.List()
+}
+
+func (s *serialScheduler) ListInternal() []Job {
+	return s.scheduler.ListInternal()
+}
+
+func (s *serialScheduler) EntryCount() (count int) {
+	s.do(func() { count = s.scheduler.EntryCount() })
+	return
+}
+
+func (s *serialScheduler) CheckConsistency() (err error) {
+	s.do(func() { err = s.scheduler.CheckConsistency() })
+	return
+}
+
+func (s *serialScheduler) Reconcile(desired []JobConfig) (res ReconcileResult, err error) {
+	s.do(func() { res, err = s.scheduler.Reconcile(desired) })
+	return
+}
+
+func (s *serialScheduler) Stats() SchedulerStats {
+	return s.scheduler.Stats()
+}
+
+func (s *serialScheduler) CheckHealth() (err error) {
+	s.do(func() { err = s.scheduler.CheckHealth() })
+	return
+}
+
+func (s *serialScheduler) JobStats(name string) (JobStats, error) {
+	return s.scheduler.JobStats(name)
+}
+
+// History is served by the recorder directly, it's not sent to the command goroutine
+func (s *serialScheduler) History(name string, limit int) ([]ScheduleRecord, error) {
+	return s.scheduler.History(name, limit)
+}
+
+func (s *serialScheduler) Timeline(within time.Duration, limit int) (runs []ScheduledRun) {
+	s.do(func() { runs = s.scheduler.Timeline(within, limit) })
+	return
+}
+
+func (s *serialScheduler) WriteMetrics(w io.Writer) error {
+	return s.scheduler.WriteMetrics(w)
+}
+
+func (s *serialScheduler) TimeUntilNext(name string) (d time.Duration, err error) {
+	s.do(func() { d, err = s.scheduler.TimeUntilNext(name) })
+	return
+}
+
+// close stop the command goroutine after the queued commands are executed
+func (s *serialScheduler) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.running {
+		s.commands <- nil
+		s.running = false
+	}
+}
+
+// Start is sent to the command goroutine, except the SchedulerStartedEvent publishing, so that the listeners
+// of the event can call the scheduler
+func (s *serialScheduler) Start() {
+	s.do(s.scheduler.start)
+	s.scheduler.reportStartup()
+}
+
+// PrepareForShutdown is not sent to the command goroutine since it waits for running jobs,
+// which may call the scheduler
+func (s *serialScheduler) PrepareForShutdown() {
+	s.scheduler.PrepareForShutdown()
+}
+
+// Stop is not sent to the command goroutine since it may wait for running jobs (see SetDrain),
+// which may call the scheduler
+func (s *serialScheduler) Stop() {
+	s.scheduler.Stop()
+	s.close()
+}
+
+// StopWithTimeout is not sent to the command goroutine for the same reason as Stop
+func (s *serialScheduler) StopWithTimeout(timeout time.Duration) {
+	s.scheduler.StopWithTimeout(timeout)
+	s.close()
+}
+
+func (s *serialScheduler) LockManagerBuilder(builder LockManagerBuilder) {
+	s.do(func() { s.scheduler.LockManagerBuilder(builder) })
+}
+
+func (s *serialScheduler) SetClock(clock Clock) {
+	s.do(func() { s.scheduler.SetClock(clock) })
+}
+
+func (s *serialScheduler) SetRecorder(recorder Recorder) {
+	s.do(func() { s.scheduler.SetRecorder(recorder) })
+}
+
+func (s *serialScheduler) SetSpecRewriter(rewriter SpecRewriter) {
+	s.do(func() { s.scheduler.SetSpecRewriter(rewriter) })
+}
+
+func (s *serialScheduler) OnLockLost(handler func(name string)) {
+	s.do(func() { s.scheduler.OnLockLost(handler) })
+}
+
+func (s *serialScheduler) SetLockRefreshInterval(interval time.Duration) {
+	s.do(func() { s.scheduler.SetLockRefreshInterval(interval) })
+}
+
+func (s *serialScheduler) SetWaitForLeadership(timeout time.Duration) {
+	s.do(func() { s.scheduler.SetWaitForLeadership(timeout) })
+}
+
+func (s *serialScheduler) SetCronMode(mode CronMode) {
+	s.do(func() { s.scheduler.SetCronMode(mode) })
+}
+
+func (s *serialScheduler) ValidatePlan(plan string) (err error) {
+	s.do(func() { err = s.scheduler.ValidatePlan(plan) })
+	return
+}
+
+func (s *serialScheduler) Describe(plan string) (desc string, err error) {
+	s.do(func() { desc, err = s.scheduler.Describe(plan) })
+	return
+}
+
+func (s *serialScheduler) SetTracer(tracer Tracer) {
+	s.do(func() { s.scheduler.SetTracer(tracer) })
+}
+
+func (s *serialScheduler) SetParserOptions(options cron.ParseOption) {
+	s.do(func() { s.scheduler.SetParserOptions(options) })
+}
+
+func (s *serialScheduler) SetPanicHandler(handler PanicHandler) {
+	s.do(func() { s.scheduler.SetPanicHandler(handler) })
+}
+
+func (s *serialScheduler) SetPanicFormatter(formatter PanicFormatter) {
+	s.do(func() { s.scheduler.SetPanicFormatter(formatter) })
+}
+
+func (s *serialScheduler) OnBeforeRun(fn func(name string)) {
+	s.do(func() { s.scheduler.OnBeforeRun(fn) })
+}
+
+func (s *serialScheduler) OnAfterRun(fn func(name string, err error, d time.Duration)) {
+	s.do(func() { s.scheduler.OnAfterRun(fn) })
+}
+
+func (s *serialScheduler) SetExecutorPoolSize(n int) {
+	s.do(func() { s.scheduler.SetExecutorPoolSize(n) })
+}
+
+func (s *serialScheduler) SetMaxConcurrency(n int) {
+	s.do(func() { s.scheduler.SetMaxConcurrency(n) })
+}
+
+func (s *serialScheduler) SetMaxConcurrencyWait(maxWait time.Duration) {
+	s.do(func() { s.scheduler.SetMaxConcurrencyWait(maxWait) })
+}
+
+func (s *serialScheduler) SetTickSpread(window time.Duration) {
+	s.do(func() { s.scheduler.SetTickSpread(window) })
+}
+
+func (s *serialScheduler) SetRunScope(limit int) {
+	s.do(func() { s.scheduler.SetRunScope(limit) })
+}
+
+func (s *serialScheduler) SetOnReadyLockTimeout(timeout time.Duration) {
+	s.do(func() { s.scheduler.SetOnReadyLockTimeout(timeout) })
+}
+
+func (s *serialScheduler) SetDrain(timeout time.Duration, policy DrainPolicy) {
+	s.do(func() { s.scheduler.SetDrain(timeout, policy) })
+}
+
+func (s *serialScheduler) Use(middlewares ...Middleware) {
+	s.do(func() { s.scheduler.Use(middlewares...) })
+}
+
+func (s *serialScheduler) EnableEventPublishing(enabled bool) {
+	s.do(func() { s.scheduler.EnableEventPublishing(enabled) })
+}
+
+func (s *serialScheduler) SetDefaults(options ...JobOption) {
+	s.do(func() { s.scheduler.SetDefaults(options...) })
+}
+
+func (s *serialScheduler) SetLabels(labels map[string]string) (err error) {
+	s.do(func() { err = s.scheduler.SetLabels(labels) })
+	return
+}
+
+func (s *serialScheduler) SetTagConcurrency(tag string, limit int) {
+	s.do(func() { s.scheduler.SetTagConcurrency(tag, limit) })
+}
+
+func (s *serialScheduler) SetHeartbeat(interval time.Duration, publishEvent bool) {
+	s.do(func() { s.scheduler.SetHeartbeat(interval, publishEvent) })
+}
+
+func (s *serialScheduler) LastHeartbeat() (t time.Time) {
+	s.do(func() { t = s.scheduler.LastHeartbeat() })
+	return
+}
+
+// runJob execute the job synchronously, it's not sent to the command goroutine since
+// the job may call the scheduler
+func (s *serialScheduler) runJob(name string) error {
+	return s.scheduler.runJob(name)
+}
@@ -0,0 +1,19 @@
+package scheduler
+
+import (
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+func (c *schedulerImpl) PrepareForShutdown() {
+	if infra.DEBUG {
+		log.Debugf("[glacier] scheduler is preparing for shutdown, waiting for running jobs...")
+	}
+
+	c.markStopped()
+	<-c.stopCron().Done()
+
+	if names := c.releaseLocks(); len(names) > 0 {
+		c.publish(LeadershipReleasedEvent{Jobs: names, Time: c.clock.Now()})
+	}
+}
@@ -0,0 +1,30 @@
+package scheduler
+
+// Reasons for skipped executions, see Job.LastSkipReason
+const (
+	SkipReasonInactive          = "out of active hours"
+	SkipReasonBackoff           = "in failure backoff"
+	SkipReasonInitialDelay      = "in initial delay"
+	SkipReasonMinInterval       = "min interval since last run not reached"
+	SkipReasonNotLeader         = "distributed lock not acquired"
+	SkipReasonLockError         = "distributed lock error"
+	SkipReasonMutexGroup        = "mutex group is busy"
+	SkipReasonTagConcurrency    = "tag concurrency limit reached"
+	SkipReasonRunLock           = "run lock is held by previous execution"
+	SkipReasonRunning           = "previous execution is still running"
+	SkipReasonConcurrency       = "max concurrency of scheduler reached"
+	SkipReasonDependencyPending = "prerequisite job not completed in current cycle"
+	SkipReasonDependencyFailed  = "prerequisite job failed"
+	SkipReasonMiddleware        = "short-circuited by middleware"
+)
+
+// skip record the reason why the execution of job is skipped, and publish a JobSkippedEvent
+func (c *schedulerImpl) skip(job *Job, reason string) {
+	now := c.clock.Now()
+
+	job.updateState(func(st *jobState) {
+		st.lastSkipReason, st.lastSkippedAt = reason, now
+	})
+
+	c.publish(JobSkippedEvent{Name: job.Name, Reason: reason, Time: now})
+}
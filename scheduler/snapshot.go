@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/robfig/cron/v3"
+)
+
+// jobsSnapshot is an immutable view of all jobs, it's replaced as a whole when jobs are added, removed,
+// rescheduled or paused, so Info and List never wait for the scheduler lock, and never block running jobs.
+// The runtime state of jobs is not copied into it, executions update the shared jobState instead
+type jobsSnapshot struct {
+	jobs map[string]Job
+	// ids is the names of scheduled jobs indexed by their cron entry id
+	ids map[cron.EntryID]string
+	// list is all user jobs sorted by name
+	list []Job
+	// internal is all internal jobs sorted by name
+	internal []Job
+}
+
+// unlock rebuild the snapshot of jobs and release the write lock, it must be used instead of
+// c.lock.Unlock() when jobs are added, removed or rescheduled. The jobs are saved to the JobStore
+// if their plan or paused state is changed, see RestoreFrom
+func (c *schedulerImpl) unlock() {
+	prev, store := c.snapshot.Load(), c.store
+
+	snap := &jobsSnapshot{
+		jobs: make(map[string]Job, len(c.jobs)),
+		ids:  make(map[cron.EntryID]string, len(c.jobs)),
+		list: make([]Job, 0, len(c.jobs)),
+	}
+	for name, job := range c.jobs {
+		snap.jobs[name] = *job
+		if !job.Paused {
+			snap.ids[job.ID] = name
+		}
+
+		if job.Internal {
+			snap.internal = append(snap.internal, *job)
+		} else {
+			snap.list = append(snap.list, *job)
+		}
+	}
+
+	sort.Slice(snap.list, func(i, j int) bool { return snap.list[i].Name < snap.list[j].Name })
+	sort.Slice(snap.internal, func(i, j int) bool { return snap.internal[i].Name < snap.internal[j].Name })
+
+	c.snapshot.Store(snap)
+	c.lock.Unlock()
+
+	if store != nil && persistedChanged(prev.list, snap.list) {
+		c.persist(store)
+	}
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// SetTickSpread spread the executions of jobs scheduled at the same time over the window.
+//
+// When n active jobs are scheduled at the same second, the i-th of them (ordered by registration)
+// is delayed by window*i/n, so the first job runs immediately and the others are evenly staggered.
+// The spread is only applied to scheduled executions, and it's applied before any other delay of the job,
+// the random delay of WithJitter is added after the spread delay.
+func (c *schedulerImpl) SetTickSpread(window time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.tickSpread = window
+}
+
+// tickPeers is the registration order of active jobs scheduled at the same instant, it's computed once by
+// the first job fired at the instant, and reused by the others until the snapshot of jobs is replaced
+type tickPeers struct {
+	at    time.Time
+	snap  *jobsSnapshot
+	peers []uint64
+}
+
+// spread block the scheduled execution of job until its turn in the tick it's scheduled at, it returns false
+// if the scheduler is stopped during the delay, the execution should be abandoned then
+func (c *schedulerImpl) spread(job *Job, scheduled time.Time) bool {
+	c.lock.RLock()
+	window := c.tickSpread
+	c.lock.RUnlock()
+
+	if window <= 0 {
+		return true
+	}
+
+	if scheduled.IsZero() {
+		scheduled = c.clock.Now()
+	}
+
+	peers := c.tickPeers(scheduled.Truncate(time.Second))
+	if len(peers) <= 1 {
+		return true
+	}
+
+	index := sort.Search(len(peers), func(i int) bool { return peers[i] >= job.seq })
+	if index >= len(peers) || peers[index] != job.seq {
+		return true
+	}
+
+	delay := window * time.Duration(index) / time.Duration(len(peers))
+	if delay <= 0 {
+		return true
+	}
+
+	if infra.DEBUG {
+		log.WithFields(infra.Fields{"job": job.Name, "delay": delay}).Debugf("[glacier] cron job [%s] delayed %s by tick spread", job.Name, delay)
+	}
+
+	return c.delay(job, delay)
+}
+
+// tickPeers get the registration order of active jobs scheduled at the tick
+func (c *schedulerImpl) tickPeers(tick time.Time) []uint64 {
+	c.spreadLock.Lock()
+	defer c.spreadLock.Unlock()
+
+	snap := c.snapshot.Load()
+	if cached := c.spreadPeers; cached != nil && cached.snap == snap && cached.at.Equal(tick) {
+		return cached.peers
+	}
+
+	peers := make([]uint64, 0)
+	for _, j := range snap.list {
+		if j.Paused {
+			continue
+		}
+
+		sc, err := j.schedule()
+		if err != nil {
+			continue
+		}
+
+		if sc.Next(tick.Add(-time.Second)).Equal(tick) {
+			peers = append(peers, j.seq)
+		}
+	}
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	c.spreadPeers = &tickPeers{at: tick, snap: snap, peers: peers}
+
+	return peers
+}
@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// JobStats is the execution statistics of a job
+type JobStats struct {
+	// Running is the number of executions in progress
+	Running int `json:"running"`
+	// RunCount is the total number of finished executions
+	RunCount int64 `json:"run_count"`
+	// FailureCount is the total number of failed executions
+	FailureCount int64 `json:"failure_count"`
+	// ConsecutiveFailures is the number of failed executions since last success
+	ConsecutiveFailures int64 `json:"consecutive_failures"`
+	// LastRunAt is the start time of the last finished execution
+	LastRunAt time.Time `json:"last_run_at,omitempty"`
+	// LastDuration is the duration of the last finished execution
+	LastDuration time.Duration `json:"last_duration"`
+	// LastError is the error of the last finished execution, empty if it succeeded
+	LastError string `json:"last_error,omitempty"`
+	// LastResult is the result returned by the handler of the last finished execution, see ResultJobHandler
+	LastResult interface{} `json:"last_result,omitempty"`
+
+	// durations is the histogram of execution durations, it's exported by WriteMetrics
+	durations durationHistogram
+}
+
+// SchedulerStats is the aggregate statistics of all jobs in scheduler
+type SchedulerStats struct {
+	TotalJobs int `json:"total_jobs"`
+	// InternalJobs is the number of internal tasks, they are not counted in other fields
+	InternalJobs  int   `json:"internal_jobs"`
+	PausedJobs    int   `json:"paused_jobs"`
+	RunningJobs   int   `json:"running_jobs"`
+	TotalRuns     int64 `json:"total_runs"`
+	TotalFailures int64 `json:"total_failures"`
+	// FailingJobs is the number of jobs whose last execution failed
+	FailingJobs int `json:"failing_jobs"`
+	// ExecutorPoolSize is the effective size of executor pool, 0 means unlimited
+	ExecutorPoolSize int `json:"executor_pool_size"`
+}
+
+// jobState is the runtime state of a job which is changed by every execution. It's replaced as a whole with
+// compare-and-swap, so executions never take the scheduler lock to update it, and the snapshot of jobs is
+// not rebuilt for it. All copies of a job (including the ones in snapshot) share the same state, see withState
+type jobState struct {
+	stats          JobStats
+	lastSkipReason string
+	lastSkippedAt  time.Time
+	finishedAt     time.Time
+	backoffUntil   time.Time
+}
+
+func newJobState() *atomic.Pointer[jobState] {
+	state := new(atomic.Pointer[jobState])
+	state.Store(&jobState{})
+
+	return state
+}
+
+// loadState get the current runtime state of job
+func (job *Job) loadState() *jobState {
+	if job.state == nil {
+		return &jobState{}
+	}
+
+	return job.state.Load()
+}
+
+// withState return the copy of job with its runtime state filled in the exported fields
+func (job Job) withState() Job {
+	st := job.loadState()
+	job.Stats, job.Running = st.stats, st.stats.Running > 0
+	job.LastSkipReason, job.LastSkippedAt = st.lastSkipReason, st.lastSkippedAt
+
+	return job
+}
+
+// updateState apply the change to a copy of the runtime state, and replace the state with it. The change
+// may be applied more than once when the state is updated concurrently, so it must have no side effects
+func (job *Job) updateState(change func(st *jobState)) {
+	for {
+		prev := job.state.Load()
+		next := *prev
+		change(&next)
+
+		if job.state.CompareAndSwap(prev, &next) {
+			return
+		}
+	}
+}
+
+func (c *schedulerImpl) Stats() SchedulerStats {
+	snap := c.snapshot.Load()
+
+	stats := SchedulerStats{ExecutorPoolSize: c.executors.getSize(), InternalJobs: len(snap.internal)}
+	for _, job := range snap.list {
+		job = job.withState()
+
+		stats.TotalJobs++
+		if job.Paused {
+			stats.PausedJobs++
+		}
+
+		if job.Stats.Running > 0 {
+			stats.RunningJobs++
+		}
+
+		if job.Stats.ConsecutiveFailures > 0 {
+			stats.FailingJobs++
+		}
+
+		stats.TotalRuns += job.Stats.RunCount
+		stats.TotalFailures += job.Stats.FailureCount
+	}
+
+	return stats
+}
+
+func (c *schedulerImpl) JobStats(name string) (JobStats, error) {
+	job, err := c.Info(name)
+	if err != nil {
+		return JobStats{}, err
+	}
+
+	return job.Stats, nil
+}
+
+func (c *schedulerImpl) beginRun(job *Job) {
+	job.updateState(func(st *jobState) { st.stats.Running++ })
+}
+
+func (c *schedulerImpl) endRun(job *Job, startTs time.Time, result interface{}, err error) {
+	finishedAt := c.clock.Now()
+	job.updateState(func(st *jobState) {
+		st.stats.Running--
+		st.stats.RunCount++
+		st.finishedAt = finishedAt
+		st.stats.LastRunAt = startTs
+		st.stats.LastDuration = finishedAt.Sub(startTs)
+		st.stats.durations.observe(st.stats.LastDuration)
+		st.stats.LastResult = result
+		if err != nil {
+			st.stats.FailureCount++
+			st.stats.ConsecutiveFailures++
+			st.stats.LastError = err.Error()
+		} else {
+			st.stats.ConsecutiveFailures = 0
+			st.stats.LastError = ""
+		}
+
+		if backoff := job.options.failureBackoff(st.stats.ConsecutiveFailures); backoff > 0 {
+			st.backoffUntil = finishedAt.Add(backoff)
+		} else {
+			st.backoffUntil = time.Time{}
+		}
+	})
+}
+
+// inInitialDelay check whether the job is in the initial delay set by WithInitialDelay, the delay starts
+// when the scheduler starts, or when the job is added if it's added after that
+func (c *schedulerImpl) inInitialDelay(job *Job) bool {
+	if job.options.initialDelay <= 0 {
+		return false
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	activeAt := job.addedAt
+	if c.startedAt.After(activeAt) {
+		activeAt = c.startedAt
+	}
+
+	return c.clock.Now().Before(activeAt.Add(job.options.initialDelay))
+}
+
+// tooSoon check whether the time since last execution finished is less than the min interval set by WithMinInterval,
+// an execution in progress is always too soon. When it's not too soon, the slot is reserved for the caller until
+// the returned release function is called, which must be called after the execution is finished (or skipped)
+func (c *schedulerImpl) tooSoon(job *Job) (bool, func()) {
+	if job.options.minInterval <= 0 {
+		return false, func() {}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	finishedAt := job.loadState().finishedAt
+	if job.intervalReserved || (!finishedAt.IsZero() && c.clock.Now().Before(finishedAt.Add(job.options.minInterval))) {
+		return true, nil
+	}
+
+	job.intervalReserved = true
+	return false, func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		job.intervalReserved = false
+	}
+}
+
+// inBackoff check whether the job is in failure backoff
+func (c *schedulerImpl) inBackoff(job *Job) bool {
+	backoffUntil := job.loadState().backoffUntil
+	return !backoffUntil.IsZero() && c.clock.Now().Before(backoffUntil)
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+	"github.com/pkg/errors"
+)
+
+// JobStore persists the state of jobs, so that the plans and paused state changed at runtime survive restarts.
+// Save is called with all jobs added by users whenever their plan or paused state is changed.
+type JobStore interface {
+	Save(jobs []Job) error
+	Load() ([]Job, error)
+}
+
+// RestoreFrom load the jobs saved in store, and apply their plan and paused state to the jobs with the same
+// name, then save the jobs to store on every change after that. Handlers can not be persisted, so it must
+// be called after all jobs are added, the saved jobs without a registered job are skipped with a warning.
+func (c *schedulerImpl) RestoreFrom(store JobStore) error {
+	saved, err := store.Load()
+	if err != nil {
+		return errors.Wrap(err, "[glacier] load jobs from store failed")
+	}
+
+	c.lock.Lock()
+	defer c.unlock()
+
+	c.store = store
+	now := c.clock.Now()
+	for _, s := range saved {
+		job, ok := c.jobs[s.Name]
+		if !ok || job.Internal {
+			if infra.WARN {
+				log.WithFields(infra.Fields{"job": s.Name}).Warningf("[glacier] saved job [%s] is not registered, skipped", s.Name)
+			}
+
+			continue
+		}
+
+		if err := c.restoreJob(job, s, now); err != nil {
+			log.WithFields(infra.Fields{"job": s.Name, "error": err}).Errorf("[glacier] restore job [%s] failed: %v", s.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreJob apply the plan and paused state of the saved job, the caller must hold the write lock
+func (c *schedulerImpl) restoreJob(job *Job, saved Job, now time.Time) error {
+	if job.options.dynamic == nil && saved.Plan != "" && saved.Plan != job.Plan {
+		if _, err := c.parser.Parse(saved.Plan); err != nil {
+			return errors.Wrapf(err, "invalid plan %s", saved.Plan)
+		}
+
+		if err := c.reschedule(job, saved.Plan); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case saved.Paused && saved.PausedUntil.After(now):
+		return c.pauseJobUntil(job.Name, saved.PausedUntil)
+	case saved.Paused && saved.PausedUntil.IsZero():
+		return c.pauseJob(job.Name)
+	case job.Paused:
+		// the job was continued, or its pause expired while the process was down
+		return c.continueJob(job.Name)
+	}
+
+	return nil
+}
+
+// persist save the jobs to store, it's called after the write lock is released
+func (c *schedulerImpl) persist(store JobStore) {
+	c.persistLock.Lock()
+	defer c.persistLock.Unlock()
+
+	// the latest snapshot is saved, so the concurrent changes are never overwritten by an older one
+	if err := store.Save(c.snapshot.Load().list); err != nil {
+		log.Errorf("[glacier] save jobs to store failed: %v", err)
+	}
+}
+
+// persistedChanged check whether the persisted state of jobs is different
+func persistedChanged(prev, current []Job) bool {
+	if len(prev) != len(current) {
+		return true
+	}
+
+	for i := range prev {
+		p, c := prev[i], current[i]
+		if p.Name != c.Name || p.Plan != c.Plan || p.Paused != c.Paused || !p.PausedUntil.Equal(c.PausedUntil) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var jobHandlerType = reflect.TypeOf((*JobHandler)(nil)).Elem()
+
+// RegisterStruct scan the fields of struct v and add jobs for all fields with a `cron` tag.
+// The field must be a non-nil function or a JobHandler, and the following tags are supported
+//
+//	cron:"@every 5s"   plan of the job, required
+//	name:"cleanup"     name of the job, the field name will be used if absent
+//	onready:"true"     trigger the job immediately when server is ready
+//	exclusive:"true"   skip this execution if the previous one is still running, see WithoutOverlap
+//
+// Example:
+//
+//	type Jobs struct {
+//		Cleanup func(db *sql.DB) error `cron:"@every 5s" name:"cleanup" exclusive:"true"`
+//	}
+func (c *schedulerImpl) RegisterStruct(v interface{}) error {
+	return registerStruct(c, v)
+}
+
+func registerStruct(creator JobCreator, v interface{}) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("[glacier] register struct failed: %T is not a struct or pointer to struct", v)
+	}
+
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		plan, ok := field.Tag.Lookup("cron")
+		if !ok {
+			continue
+		}
+
+		if err := registerStructField(creator, val.Field(i), field, plan); err != nil {
+			return fmt.Errorf("[glacier] register struct %s failed: field %s: %v", typ.String(), field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func registerStructField(creator JobCreator, fieldVal reflect.Value, field reflect.StructField, plan string) error {
+	if plan == "" {
+		return fmt.Errorf("cron tag can not be empty")
+	}
+
+	if !field.IsExported() {
+		return fmt.Errorf("field must be exported")
+	}
+
+	if field.Type.Kind() != reflect.Func && !field.Type.Implements(jobHandlerType) {
+		return fmt.Errorf("field must be a function or a JobHandler, got %s", field.Type.String())
+	}
+
+	if fieldVal.IsZero() {
+		return fmt.Errorf("field value is nil")
+	}
+
+	name := field.Tag.Get("name")
+	if name == "" {
+		name = field.Name
+	}
+
+	onReady, err := parseBoolTag(field, "onready")
+	if err != nil {
+		return err
+	}
+
+	exclusive, err := parseBoolTag(field, "exclusive")
+	if err != nil {
+		return err
+	}
+
+	var handler interface{} = fieldVal.Interface()
+	if exclusive {
+		handler = WithoutOverlap(handler)
+	}
+
+	if onReady {
+		return creator.AddAndRunOnServerReady(name, plan, handler)
+	}
+
+	return creator.Add(name, plan, handler)
+}
+
+func parseBoolTag(field reflect.StructField, tag string) (bool, error) {
+	val, ok := field.Tag.Lookup(tag)
+	if !ok || val == "" {
+		return false, nil
+	}
+
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s tag: %s", tag, val)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hasTag check whether the job has the tag set by WithTags
+func (job Job) hasTag(tag string) bool {
+	for _, t := range job.Tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *schedulerImpl) ListByTag(tag string) []Job {
+	jobs := make([]Job, 0)
+	for _, job := range c.snapshot.Load().list {
+		if job.hasTag(tag) {
+			jobs = append(jobs, job.withState())
+		}
+	}
+
+	return jobs
+}
+
+func (c *schedulerImpl) PauseByTag(tag string) error {
+	c.lock.Lock()
+	defer c.unlock()
+
+	return c.applyByTag(tag, "pause", c.pauseJob)
+}
+
+func (c *schedulerImpl) ContinueByTag(tag string) error {
+	c.lock.Lock()
+	defer c.unlock()
+
+	return c.applyByTag(tag, "continue", c.continueJob)
+}
+
+// applyByTag apply the operation to all jobs with the tag, the errors of jobs are aggregated.
+// The caller must hold the write lock
+func (c *schedulerImpl) applyByTag(tag string, op string, fn func(name string) error) error {
+	problems := make([]string, 0)
+	for name, job := range c.jobs {
+		if job.Internal || !job.hasTag(tag) {
+			continue
+		}
+
+		if err := fn(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("[glacier] %s jobs with tag [%s] failed: %s", op, tag, strings.Join(problems, ", "))
+	}
+
+	return nil
+}
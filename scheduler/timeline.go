@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// DefaultTimelineLimit is the max number of executions returned by Timeline when no limit is given
+const DefaultTimelineLimit = 1000
+
+// ScheduledRun is an upcoming execution of job
+type ScheduledRun struct {
+	Name string    `json:"name"`
+	At   time.Time `json:"at"`
+}
+
+func (c *schedulerImpl) Timeline(within time.Duration, limit int) []ScheduledRun {
+	if limit <= 0 {
+		limit = DefaultTimelineLimit
+	}
+
+	now := c.clock.Now()
+	end := now.Add(within)
+
+	c.lock.RLock()
+	jobs := make([]Job, 0, len(c.jobs))
+	runs := make([]ScheduledRun, 0)
+	for _, job := range c.jobs {
+		if job.Paused || job.Internal {
+			continue
+		}
+
+		// only the next execution of dynamic job is known
+		if job.options.dynamic != nil {
+			if next := c.cr.Entry(job.ID).Next; !next.IsZero() && !next.After(end) {
+				runs = append(runs, ScheduledRun{Name: job.Name, At: next})
+			}
+
+			continue
+		}
+
+		jobs = append(jobs, *job)
+	}
+	c.lock.RUnlock()
+
+	for _, job := range jobs {
+		sc, err := job.schedule()
+		if err != nil {
+			log.WithFields(infra.Fields{"job": job.Name, "error": err}).Errorf("[glacier] can not parse plan for job [%s]: %v", job.Name, err)
+			continue
+		}
+
+		// the executions of a job after its first limit ones are never returned
+		for next, n := sc.Next(now), 0; !next.IsZero() && !next.After(end) && n < limit; next, n = sc.Next(next), n+1 {
+			runs = append(runs, ScheduledRun{Name: job.Name, At: next})
+		}
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		if runs[i].At.Equal(runs[j].At) {
+			return runs[i].Name < runs[j].Name
+		}
+
+		return runs[i].At.Before(runs[j].At)
+	})
+
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+
+	return runs
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Tracer start a span for an attempt of job execution, e.g. with an OpenTelemetry tracer
+//
+//	s.SetTracer(func(ctx context.Context, name string) (context.Context, func(err error)) {
+//		ctx, span := tracer.Start(ctx, "cron "+name, trace.WithAttributes(attribute.String("run_id", scheduler.RunIDFromContext(ctx))))
+//		return ctx, func(err error) {
+//			if err != nil {
+//				span.RecordError(err)
+//			}
+//			span.End()
+//		}
+//	})
+//
+// The returned context is passed to the handler, and the returned function is called with the result
+// of the attempt. When WithRetry is used, every attempt starts a new span with the same run ID.
+type Tracer func(ctx context.Context, name string) (context.Context, func(err error))
+
+// newRunID generate a random ID for an execution of job
+func newRunID() string {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(buf[:])
+}
+
+// RunIDFromContext get the ID of the current execution from the run context, the same ID is included in
+// the logs and events of scheduler for this execution (JobStartedEvent, JobCompletedEvent, JobFailedEvent).
+//
+// The ID is not attached to the events published by the handler through event.Manager: events are plain
+// values delivered without any context, and the scheduler can not modify them. Put the ID into the event
+// explicitly if the listener needs it:
+//
+//	s.MustAdd("sync", "@every 1m", func(ctx context.Context, publisher event.Publisher) error {
+//		return publisher.Publish(SyncedEvent{RunID: scheduler.RunIDFromContext(ctx)})
+//	})
+func RunIDFromContext(ctx context.Context) string {
+	runID, _ := ctx.Value(runIDKey).(string)
+	return runID
+}
+
+func (c *schedulerImpl) SetTracer(tracer Tracer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.tracer = tracer
+}
+
+// startSpan start a span for the attempt by the tracer, the returned function must be called with the result
+func (c *schedulerImpl) startSpan(ctx context.Context, job *Job) (context.Context, func(err error)) {
+	c.lock.RLock()
+	tracer := c.tracer
+	c.lock.RUnlock()
+
+	if tracer == nil {
+		return ctx, func(error) {}
+	}
+
+	return tracer(ctx, job.Name)
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// Trigger run the job immediately in a new goroutine, without waiting for its next schedule.
+// The execution goes through the same wrapper as scheduled runs, so the distributed lock,
+// panic recovery and stats apply as well. Like scheduled runs, Stop and PrepareForShutdown wait for it
+func (c *schedulerImpl) Trigger(name string) error {
+	c.lock.RLock()
+	job, err := c.userJob(name)
+	c.lock.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	if infra.DEBUG {
+		log.WithFields(infra.Fields{"job": name}).Debugf("[glacier] cron job [%s] is triggered manually", name)
+	}
+
+	c.triggered.add()
+	go func() {
+		defer c.triggered.done()
+		job.handler()
+	}()
+
+	return nil
+}
+
+func (c *schedulerImpl) MustTrigger(name string) {
+	if err := c.Trigger(name); err != nil {
+		panic(err)
+	}
+}
+
+// runTracker tracks the executions started outside of cron, like the ones started by Trigger
+type runTracker struct {
+	lock    sync.Mutex
+	running int
+	// idle is closed when there is no execution running
+	idle chan struct{}
+}
+
+func (t *runTracker) add() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.running == 0 {
+		t.idle = make(chan struct{})
+	}
+
+	t.running++
+}
+
+func (t *runTracker) done() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.running--
+	if t.running == 0 {
+		close(t.idle)
+	}
+}
+
+// wait return a channel which is closed when there is no execution running
+func (t *runTracker) wait() <-chan struct{} {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.running == 0 {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+
+	return t.idle
+}
+
+// stopCron stop cron from scheduling new executions, the returned context is done when the executions
+// in progress are all finished, including the ones started by Trigger
+func (c *schedulerImpl) stopCron() context.Context {
+	cronDone := c.cr.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-cronDone.Done()
+		<-c.triggered.wait()
+		cancel()
+	}()
+
+	return ctx
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// webhookTimeout is the timeout for delivering a webhook
+const webhookTimeout = 10 * time.Second
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// WebhookPayload is the JSON payload posted to the webhook set by WithWebhook
+type WebhookPayload struct {
+	Job        string    `json:"job"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	// Labels is the labels of scheduler, see SetLabels
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// notifyWebhook post the result of an execution to the webhook of job asynchronously
+func (c *schedulerImpl) notifyWebhook(job *Job, startTs time.Time, d time.Duration, err error) {
+	url, payload := job.options.webhookOnSuccess, WebhookPayload{
+		Job:        job.Name,
+		Status:     "success",
+		StartedAt:  startTs,
+		DurationMs: d.Milliseconds(),
+		Labels:     c.getLabels(),
+	}
+
+	if err != nil {
+		url, payload.Status, payload.Error = job.options.webhookOnFailure, "failure", err.Error()
+	}
+
+	if url == "" {
+		return
+	}
+
+	go func() {
+		if err := postWebhook(url, payload); err != nil {
+			log.WithFields(infra.Fields{"job": job.Name, "url": url, "error": err}).Errorf("[glacier] deliver webhook for cron job [%s] to %s failed: %v", job.Name, url, err)
+			return
+		}
+
+		if infra.DEBUG {
+			log.WithFields(infra.Fields{"job": job.Name, "url": url}).Debugf("[glacier] webhook for cron job [%s] delivered to %s", job.Name, url)
+		}
+	}()
+}
+
+func postWebhook(url string, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
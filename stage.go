@@ -75,7 +75,20 @@ func (impl *framework) diBindStage(ctx context.Context, flagCtx infra.FlagContex
 		if impl.gracefulBuilder != nil {
 			return impl.gracefulBuilder()
 		}
-		return graceful.NewWithDefault(conf.ShutdownTimeout)
+
+		if impl.shutdownSignals == nil && impl.reloadSignals == nil {
+			return graceful.NewWithDefault(conf.ShutdownTimeout)
+		}
+
+		shutdownSignals, reloadSignals := impl.shutdownSignals, impl.reloadSignals
+		if shutdownSignals == nil {
+			shutdownSignals = graceful.DefaultShutdownSignals
+		}
+		if reloadSignals == nil {
+			reloadSignals = graceful.DefaultReloadSignals
+		}
+
+		return graceful.NewWithSignal(reloadSignals, shutdownSignals, conf.ShutdownTimeout)
 	})
 
 	// 注册全局对象
@@ -93,6 +106,17 @@ func (impl *framework) diBindStage(ctx context.Context, flagCtx infra.FlagContex
 		impl.cc.MustPrototypeOverride(i)
 	}
 
+	if infra.DEBUG && len(impl.keyedBindings) > 0 {
+		impl.pushGraphvizNode("add keyed bindings to container", false)
+	}
+	for _, b := range impl.keyedBindings {
+		if b.prototype {
+			impl.cc.MustPrototypeWithKeyOverride(infra.KeyOf(b.key), b.init)
+		} else {
+			impl.cc.MustSingletonWithKeyOverride(infra.KeyOf(b.key), b.init)
+		}
+	}
+
 	// 完成预绑定对象的绑定
 	if impl.preBinder != nil {
 		if infra.DEBUG {
@@ -189,6 +213,11 @@ func (impl *framework) Start(flagCtx infra.FlagContext) error {
 				return err
 			}
 
+			// 执行启动前钩子，任何一个钩子返回错误都会终止启动
+			if err := impl.beforeStartStage(resolver); err != nil {
+				return err
+			}
+
 			// 启动 Daemon Providers
 			if err := impl.startDaemonProviders(ctx, &wg); err != nil {
 				return err
@@ -208,6 +237,7 @@ func (impl *framework) Start(flagCtx infra.FlagContext) error {
 		impl.updateGlacierStatus(Started)
 		impl.readyStage(resolver, gf)
 
+		defer impl.stopStage(resolver)
 		defer impl.shutdownHandler(conf, &wg)
 		if infra.DEBUG {
 			gf.AddPreShutdownHandler(func() {
@@ -260,6 +290,44 @@ func (impl *framework) readyStage(resolver infra.Resolver, gf infra.Graceful) {
 	}
 }
 
+// beforeStartStage invoke the BeforeServerStart hooks in registration order
+func (impl *framework) beforeStartStage(resolver infra.Resolver) error {
+	impl.lock.RLock()
+	hooks := impl.beforeServerStartHooks
+	impl.lock.RUnlock()
+
+	for i, hook := range hooks {
+		if infra.DEBUG {
+			impl.pushGraphvizNode(fmt.Sprintf("invoke beforeServerStart hook %d", i), false).Style = infra.GraphvizNodeStyleHook
+			log.Debugf("[glacier] invoke beforeServerStart hook %d", i)
+		}
+
+		if err := hook(resolver); err != nil {
+			return fmt.Errorf("[glacier] beforeServerStart hook %d failed: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// stopStage invoke the OnServerStop hooks in LIFO order after all modules stopped
+func (impl *framework) stopStage(resolver infra.Resolver) {
+	impl.lock.RLock()
+	hooks := impl.onServerStopHooks
+	impl.lock.RUnlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if infra.DEBUG {
+			impl.pushGraphvizNode("invoke onServerStop hook: "+hooks[i].name, false).Style = infra.GraphvizNodeStyleHook
+			log.Debugf("[glacier] invoke onServerStop hook [%s]", hooks[i].name)
+		}
+
+		if err := resolver.Resolve(hooks[i].fn); err != nil {
+			log.Errorf("[glacier] onServerStop hook [%s] failed: %v", hooks[i].name, err)
+		}
+	}
+}
+
 func (impl *framework) shutdownHandler(conf *Config, wg *sync.WaitGroup) {
 	if infra.DEBUG {
 		impl.pushGraphvizNode("shutdown", false)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/mylxsw/glacier"
 	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
 	"github.com/urfave/cli/v2"
 	"github.com/urfave/cli/v2/altsrc"
 )
@@ -92,6 +93,36 @@ func (app *App) WithLogger(logger infra.Logger) *App {
 	return app
 }
 
+// WithEnvironment set the environment of application, it's bound to container as infra.Environment,
+// and used as default label (env) by the metrics of modules like scheduler and web, and the structured logs
+func (app *App) WithEnvironment(name string) *App {
+	app.gcr.Singleton(func() infra.Environment { return infra.Environment(name) })
+	log.AddDefaultFields(infra.Fields{"env": name})
+	return app
+}
+
+// WithServiceName set the name of service, it's bound to container as infra.ServiceName,
+// and used as default label (service) by the metrics of modules like scheduler and web, and the structured logs
+func (app *App) WithServiceName(name string) *App {
+	app.gcr.Singleton(func() infra.ServiceName { return infra.ServiceName(name) })
+	log.AddDefaultFields(infra.Fields{"service": name})
+	return app
+}
+
+// WithHealthCheck serve a health check endpoint at path by the web module, it responds the health of all
+// components created by checkers in JSON, with status code 200 if all of them are healthy, otherwise 503
+func (app *App) WithHealthCheck(path string, checkers ...func(resolver infra.Resolver) infra.HealthChecker) *App {
+	app.gcr.Singleton(func() *infra.HealthCheck { return &infra.HealthCheck{Path: path, Checkers: checkers} })
+	return app
+}
+
+// WithPrometheus serve a Prometheus metrics endpoint at path by the web module, it exports the request metrics
+// of the web module (labeled by route, method and status), and the metrics of all components created by collectors
+func (app *App) WithPrometheus(path string, collectors ...func(resolver infra.Resolver) infra.MetricsCollector) *App {
+	app.gcr.Singleton(func() *infra.Metrics { return &infra.Metrics{Path: path, Collectors: collectors} })
+	return app
+}
+
 func MustRun(app *App) {
 	if err := app.Run(os.Args); err != nil {
 		panic(err)
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"os"
+
 	"github.com/mylxsw/glacier/infra"
 )
 
@@ -29,6 +31,16 @@ func (app *App) Graceful(builder func() infra.Graceful) *App {
 	return app
 }
 
+func (app *App) WithShutdownSignals(sigs ...os.Signal) *App {
+	app.gcr.WithShutdownSignals(sigs...)
+	return app
+}
+
+func (app *App) WithReloadSignals(sigs ...os.Signal) *App {
+	app.gcr.WithReloadSignals(sigs...)
+	return app
+}
+
 func (app *App) Start(cliCtx infra.FlagContext) error {
 	return app.gcr.Start(cliCtx)
 }
@@ -38,10 +50,18 @@ func (app *App) Init(f func(c infra.FlagContext) error) *App {
 	return app
 }
 
+func (app *App) BeforeServerStart(f func(resolver infra.Resolver) error) {
+	app.gcr.BeforeServerStart(f)
+}
+
 func (app *App) OnServerReady(ffs ...interface{}) {
 	app.gcr.OnServerReady(ffs...)
 }
 
+func (app *App) OnServerStop(ffs ...interface{}) {
+	app.gcr.OnServerStop(ffs...)
+}
+
 func (app *App) BeforeServerStop(f func(cc infra.Resolver) error) *App {
 	app.gcr.BeforeServerStop(f)
 	return app
@@ -57,6 +77,16 @@ func (app *App) Prototype(ins ...interface{}) *App {
 	return app
 }
 
+func (app *App) SingletonWithKey(key string, ins interface{}) *App {
+	app.gcr.SingletonWithKey(key, ins)
+	return app
+}
+
+func (app *App) PrototypeWithKey(key string, ins interface{}) *App {
+	app.gcr.PrototypeWithKey(key, ins)
+	return app
+}
+
 func (app *App) Resolve(resolver interface{}) error {
 	return app.gcr.Resolve(resolver)
 }
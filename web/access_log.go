@@ -0,0 +1,85 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// defaultOmittedHeaders 访问日志中默认不记录的敏感请求头
+var defaultOmittedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// accessLogHandler 记录每个请求的访问日志，并捕获 handler 中的 panic
+// 调试模式（infra.DEBUG）下，会额外记录除了 omittedHeaders 之外的所有请求头
+func accessLogHandler(handler http.Handler, omittedHeaders []string) http.Handler {
+	omitted := make(map[string]bool, len(omittedHeaders))
+	for _, h := range omittedHeaders {
+		omitted[http.CanonicalHeaderKey(h)] = true
+	}
+
+	handler = recoverHandler(handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTs := time.Now()
+		path := r.URL.Path
+
+		rw := newResponseRecorder(w)
+		defer func() {
+			fields := infra.Fields{
+				"method":     r.Method,
+				"path":       path,
+				"status":     rw.Status(),
+				"latency_ms": time.Since(startTs).Seconds() * 1000,
+				"client_ip":  clientIP(r),
+			}
+
+			if infra.DEBUG {
+				fields["headers"] = formatHeaders(r.Header, omitted)
+				log.WithFields(fields).Debugf("[glacier] http request %s %s", r.Method, path)
+				return
+			}
+
+			log.WithFields(fields).Infof("[glacier] http request %s %s", r.Method, path)
+		}()
+
+		handler.ServeHTTP(rw, r)
+	})
+}
+
+// clientIP 获取客户端 IP，优先使用 X-Forwarded-For，X-Real-IP 请求头
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+func formatHeaders(header http.Header, omitted map[string]bool) string {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		if !omitted[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+strings.Join(header[k], ","))
+	}
+
+	return strings.Join(pairs, "; ")
+}
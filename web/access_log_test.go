@@ -0,0 +1,117 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// fieldsLogger 记录所有通过 log.WithFields 输出的日志字段
+type fieldsLogger struct {
+	infra.Logger
+	lock    *sync.Mutex
+	entries *[]infra.Fields
+}
+
+func newFieldsLogger() fieldsLogger {
+	return fieldsLogger{Logger: log.StdLogger(), lock: &sync.Mutex{}, entries: &[]infra.Fields{}}
+}
+
+func (l fieldsLogger) WithFields(fields infra.Fields) infra.Logger {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	*l.entries = append(*l.entries, fields)
+	return l.Logger
+}
+
+func (l fieldsLogger) last() infra.Fields {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(*l.entries) == 0 {
+		return nil
+	}
+
+	return (*l.entries)[len(*l.entries)-1]
+}
+
+func withLogger(t *testing.T, logger infra.Logger) {
+	log.SetDefaultLogger(logger)
+	t.Cleanup(func() { log.SetDefaultLogger(log.StdLogger()) })
+}
+
+func TestAccessLogStatus(t *testing.T) {
+	logger := newFieldsLogger()
+	withLogger(t, logger)
+
+	handler := accessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusInternalServerError)
+	}), defaultOmittedHeaders)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	fields := logger.last()
+	if fields["status"] != http.StatusCreated || fields["method"] != http.MethodPost || fields["path"] != "/users" {
+		t.Errorf("access log should record the first status written, got %v", fields)
+	}
+}
+
+func TestAccessLogPanic(t *testing.T) {
+	logger := newFieldsLogger()
+	withLogger(t, logger)
+
+	handler := accessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom: password=secret")
+	}), defaultOmittedHeaders)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("panic should be responded with 500, got %d", w.Code)
+	}
+
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("panic detail should not be responded to clients, got %q", w.Body.String())
+	}
+
+	if entries := *logger.entries; len(entries) < 2 || entries[0]["error"] != "boom: password=secret" {
+		t.Errorf("panic detail should be logged, got %v", entries)
+	}
+
+	if fields := logger.last(); fields["status"] != http.StatusInternalServerError {
+		t.Errorf("access log should record the status of panic, got %v", fields)
+	}
+}
+
+func TestAccessLogOmittedHeaders(t *testing.T) {
+	infra.DEBUG = true
+	defer func() { infra.DEBUG = false }()
+
+	logger := newFieldsLogger()
+	withLogger(t, logger)
+
+	// the custom omitted headers are added to the default ones
+	var conf Config
+	SetAccessLogOption("X-Api-Key")(nil, &conf)
+	handler := accessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), conf.omittedHeaders)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Cookie", "session=secret")
+	r.Header.Set("X-Api-Key", "secret")
+	r.Header.Set("X-Request-Id", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	headers, _ := logger.last()["headers"].(string)
+	if strings.Contains(headers, "secret") || !strings.Contains(headers, "X-Request-Id=abc") {
+		t.Errorf("sensitive headers should be omitted, got %q", headers)
+	}
+}
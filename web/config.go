@@ -1,6 +1,7 @@
 package web
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"time"
@@ -18,8 +19,16 @@ type Config struct {
 	routeHandler        RouteHandler
 	serverConfigHandler ServerConfigHandler
 	muxRouteHandler     MuxRouteHandler
+	httpHandlerBuilder  HttpHandlerBuilder
 	initHandler         InitHandler
 	exceptionHandler    ExceptionHandler
+	leaderLock          LeaderLock
+	leaderCheckInterval time.Duration
+	tlsCertFile         string
+	tlsKeyFile          string
+	tlsConfig           *tls.Config
+	accessLog           bool
+	omittedHeaders      []string
 
 	MultipartFormMaxMemory int64  // Multipart-form 解析占用最大内存
 	ViewTemplatePathPrefix string // 视图模板目录
@@ -31,6 +40,12 @@ type Config struct {
 	HttpIdleTimeout       time.Duration
 	HttpReadTimeout       time.Duration
 	HttpReadHeaderTimeout time.Duration
+	ShutdownTimeout       time.Duration // 服务停止时，等待处理中的请求完成的最长时间
+}
+
+// tlsEnabled 是否启用了 HTTPS
+func (conf *Config) tlsEnabled() bool {
+	return conf.tlsCertFile != "" || conf.tlsConfig != nil
 }
 
 // DefaultConfig create a default config
@@ -41,5 +56,6 @@ func DefaultConfig() *Config {
 		TempDir:                "/tmp",
 		TempFilePattern:        "glacier-files-",
 		IgnoreLastSlash:        false,
+		ShutdownTimeout:        5 * time.Second,
 	}
 }
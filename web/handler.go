@@ -0,0 +1,99 @@
+package web
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// HttpHandlerBuilder 创建自定义的 http.Handler，比如 gin，chi 等第三方框架的路由对象，
+// 可以通过 resolver 从容器中获取依赖
+type HttpHandlerBuilder func(resolver infra.Resolver) (http.Handler, error)
+
+// recoverHandler 捕获 handler 中的 panic，记录日志之后返回 500 Internal Server Error
+// panic 的详细信息只记录在日志中，不会返回给客户端，避免泄露内部信息
+func recoverHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				log.WithFields(infra.Fields{
+					"method": r.Method,
+					"path":   r.URL.Path,
+					"error":  err,
+					"stack":  string(debug.Stack()),
+				}).Errorf("[glacier] http handler panic: %s %s", r.Method, r.URL.Path)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// responseRecorder 记录响应状态码的 http.ResponseWriter，同时保留 http.Flusher，http.Hijacker 的支持（websocket）
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+// Status 返回响应状态码，handler 没有显式调用 WriteHeader 时为 200
+func (rw *responseRecorder) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+
+	return rw.status
+}
+
+func (rw *responseRecorder) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *responseRecorder) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rw *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the http.ResponseWriter does not support hijacking")
+	}
+
+	if rw.status == 0 {
+		rw.status = http.StatusSwitchingProtocols
+	}
+
+	return hijacker.Hijack()
+}
+
+// Unwrap 返回原始的 http.ResponseWriter，用于 http.ResponseController
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// healthCheckTimeout is the max time for checking health of all components
+const healthCheckTimeout = 5 * time.Second
+
+type healthStatus struct {
+	Status     string            `json:"status"`
+	Components []componentHealth `json:"components"`
+}
+
+type componentHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// newHealthHandler create a http.Handler which checks all components, it responds 200 when all of
+// them are healthy, otherwise 503
+func newHealthHandler(cc infra.Resolver, hc *infra.HealthCheck) http.Handler {
+	checkers := make([]infra.HealthChecker, 0, len(hc.Checkers))
+	for _, builder := range hc.Checkers {
+		checkers = append(checkers, builder(cc))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		code, result := http.StatusOK, healthStatus{Status: "ok", Components: make([]componentHealth, 0, len(checkers))}
+		for _, checker := range checkers {
+			component := componentHealth{Name: checker.Name(), Status: "ok"}
+			if err := checker.CheckHealth(ctx); err != nil {
+				code, result.Status = http.StatusServiceUnavailable, "unhealthy"
+				component.Status, component.Error = "unhealthy", err.Error()
+			}
+
+			result.Components = append(result.Components, component)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Errorf("[glacier] write health check response failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// DefaultLeaderCheckInterval is the default interval to check the leader lock
+const DefaultLeaderCheckInterval = 10 * time.Second
+
+// LeaderLock is a distributed lock used to elect the leader which serves traffic,
+// it has the same signature as scheduler.LockManager, so the same implementation can be used.
+// TryLock is called periodically, it should refresh the lock when it's already held by current node.
+type LeaderLock interface {
+	TryLock(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+// leaderGate only lets requests through when current node holds the leader lock
+type leaderGate struct {
+	lock     LeaderLock
+	interval time.Duration
+	leader   int32
+}
+
+func newLeaderGate(lock LeaderLock, interval time.Duration) *leaderGate {
+	if interval <= 0 {
+		interval = DefaultLeaderCheckInterval
+	}
+
+	return &leaderGate{lock: lock, interval: interval}
+}
+
+func (gate *leaderGate) isLeader() bool {
+	return atomic.LoadInt32(&gate.leader) == 1
+}
+
+// run check the leader lock until ctx is done, the lock is released when it returns
+func (gate *leaderGate) run(ctx context.Context) {
+	ticker := time.NewTicker(gate.interval)
+	defer ticker.Stop()
+
+	for {
+		gate.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			if gate.isLeader() {
+				atomic.StoreInt32(&gate.leader, 0)
+				if err := gate.lock.Release(context.Background()); err != nil {
+					log.Errorf("[glacier] http server can not release leader lock: %v", err)
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (gate *leaderGate) check(ctx context.Context) {
+	err := gate.lock.TryLock(ctx)
+	if err == nil {
+		if atomic.CompareAndSwapInt32(&gate.leader, 0, 1) && infra.DEBUG {
+			log.Debugf("[glacier] http server becomes leader, start serving traffic")
+		}
+
+		return
+	}
+
+	if atomic.CompareAndSwapInt32(&gate.leader, 1, 0) {
+		log.Warningf("[glacier] http server lost leadership, stop serving traffic: %v", err)
+	}
+}
+
+// wrap the handler, requests are rejected with 503 Service Unavailable when current node is not leader
+func (gate *leaderGate) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !gate.isLeader() {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "not leader", http.StatusServiceUnavailable)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeLeaderLock struct {
+	lock     sync.Mutex
+	err      error
+	released int
+}
+
+func (l *fakeLeaderLock) TryLock(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.err
+}
+
+func (l *fakeLeaderLock) Release(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.released++
+	return nil
+}
+
+func (l *fakeLeaderLock) fail(err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.err = err
+}
+
+func (l *fakeLeaderLock) releasedTimes() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.released
+}
+
+func TestLeaderGate(t *testing.T) {
+	lock := &fakeLeaderLock{err: errors.New("held by another node")}
+	gate := newLeaderGate(lock, 10*time.Millisecond)
+	handler := gate.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		gate.run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if w := serve(); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("follower should reject requests with 503, got %d", w.Code)
+	}
+
+	lock.fail(nil)
+	time.Sleep(50 * time.Millisecond)
+	if w := serve(); w.Code != http.StatusNoContent {
+		t.Errorf("leader should serve requests, got %d", w.Code)
+	}
+
+	lock.fail(errors.New("lock lost"))
+	time.Sleep(50 * time.Millisecond)
+	if w := serve(); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("node lost leadership should reject requests, got %d", w.Code)
+	}
+
+	lock.fail(nil)
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	<-stopped
+
+	if w := serve(); w.Code != http.StatusServiceUnavailable || lock.releasedTimes() != 1 {
+		t.Errorf("leader lock should be released when the server stops, got %d, released %d", w.Code, lock.releasedTimes())
+	}
+}
@@ -0,0 +1,188 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mylxsw/glacier/infra"
+	"github.com/mylxsw/glacier/log"
+)
+
+// metricsContentType 指标接口响应的 Content-Type
+const metricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// requestDurationBuckets 请求耗时直方图的桶上限（秒）
+var requestDurationBuckets = [...]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metricsLabelEscaper 按照 OpenMetrics 文本格式的要求转义 label 值
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type requestMetricsKey struct {
+	route  string
+	method string
+	status int
+}
+
+type requestMetricsValue struct {
+	buckets [len(requestDurationBuckets)]int64
+	count   int64
+	sum     float64
+}
+
+// requestMetrics 记录 http 请求的指标，route label 使用路由规则（比如 /users/{id}）而不是请求路径，避免 label 数量无限增长
+type requestMetrics struct {
+	lock   sync.Mutex
+	router *mux.Router
+	values map[requestMetricsKey]*requestMetricsValue
+	// constLabels 所有指标都包含的 label（比如 env，service），已经格式化为 k="v", 的形式
+	constLabels string
+}
+
+// newRequestMetrics 创建请求指标，labels 会添加到所有的指标中
+func newRequestMetrics(router *mux.Router, labels map[string]string) *requestMetrics {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var constLabels strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&constLabels, `%s="%s",`, k, metricsLabelEscaper.Replace(labels[k]))
+	}
+
+	return &requestMetrics{router: router, values: make(map[requestMetricsKey]*requestMetricsValue), constLabels: constLabels.String()}
+}
+
+// wrap 返回一个记录请求指标的 http.Handler
+func (m *requestMetrics) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTs := time.Now()
+		rw := newResponseRecorder(w)
+		defer func() {
+			// the route is matched after serving, since the path may be modified by router (IgnoreLastSlash)
+			m.observe(requestMetricsKey{route: m.route(r), method: r.Method, status: rw.Status()}, time.Since(startTs))
+		}()
+
+		handler.ServeHTTP(rw, r)
+	})
+}
+
+// route 获取请求匹配的路由规则，未匹配到任何路由时返回 none
+func (m *requestMetrics) route(r *http.Request) string {
+	var match mux.RouteMatch
+	if !m.router.Match(r, &match) || match.Route == nil {
+		return "none"
+	}
+
+	if tpl, err := match.Route.GetPathTemplate(); err == nil {
+		return tpl
+	}
+
+	return "none"
+}
+
+func (m *requestMetrics) observe(key requestMetricsKey, elapse time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	value, ok := m.values[key]
+	if !ok {
+		value = &requestMetricsValue{}
+		m.values[key] = value
+	}
+
+	seconds := elapse.Seconds()
+	for i, le := range requestDurationBuckets {
+		if seconds <= le {
+			value.buckets[i]++
+		}
+	}
+
+	value.count++
+	value.sum += seconds
+}
+
+// write 以 OpenMetrics 文本格式输出请求指标（不包含 # EOF）
+func (m *requestMetrics) write(buf *bytes.Buffer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	keys := make([]requestMetricsKey, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+
+		return keys[i].status < keys[j].status
+	})
+
+	buf.WriteString("# TYPE glacier_http_requests counter\n# HELP glacier_http_requests Number of http requests.\n")
+	for _, k := range keys {
+		fmt.Fprintf(buf, "glacier_http_requests_total%s %d\n", m.labels(k, ""), m.values[k].count)
+	}
+
+	buf.WriteString("# TYPE glacier_http_request_duration_seconds histogram\n# HELP glacier_http_request_duration_seconds Duration of http requests.\n")
+	for _, k := range keys {
+		value := m.values[k]
+		for i, le := range requestDurationBuckets {
+			fmt.Fprintf(buf, "glacier_http_request_duration_seconds_bucket%s %d\n", m.labels(k, strconv.FormatFloat(le, 'g', -1, 64)), value.buckets[i])
+		}
+
+		fmt.Fprintf(buf, "glacier_http_request_duration_seconds_bucket%s %d\n", m.labels(k, "+Inf"), value.count)
+		fmt.Fprintf(buf, "glacier_http_request_duration_seconds_count%s %d\n", m.labels(k, ""), value.count)
+		fmt.Fprintf(buf, "glacier_http_request_duration_seconds_sum%s %s\n", m.labels(k, ""), strconv.FormatFloat(value.sum, 'g', -1, 64))
+	}
+}
+
+// labels 格式化 label，le 不为空时追加 le label
+func (m *requestMetrics) labels(k requestMetricsKey, le string) string {
+	res := fmt.Sprintf(`{%sroute="%s",method="%s",status="%d"`, m.constLabels, metricsLabelEscaper.Replace(k.route), metricsLabelEscaper.Replace(k.method), k.status)
+	if le != "" {
+		res += fmt.Sprintf(`,le="%s"`, le)
+	}
+
+	return res + "}"
+}
+
+// newMetricsHandler 创建指标接口的 http.Handler，输出 http 请求指标以及所有 collector 的指标
+func newMetricsHandler(cc infra.Resolver, conf *infra.Metrics, metrics *requestMetrics) http.Handler {
+	collectors := make([]infra.MetricsCollector, 0, len(conf.Collectors))
+	for _, builder := range conf.Collectors {
+		collectors = append(collectors, builder(cc))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		metrics.write(&buf)
+
+		for _, collector := range collectors {
+			if err := collector.CollectMetrics(&buf); err != nil {
+				log.Errorf("[glacier] collect metrics failed: %v", err)
+				http.Error(w, "collect metrics failed", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		buf.WriteString("# EOF\n")
+
+		w.Header().Set("Content-Type", metricsContentType)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			log.Errorf("[glacier] write metrics response failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package web
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRequestMetricsLabels(t *testing.T) {
+	metrics := newRequestMetrics(mux.NewRouter(), map[string]string{"service": "billing", "env": "prod"})
+	metrics.observe(requestMetricsKey{route: "/users/{id}", method: "GET", status: 200}, 20*time.Millisecond)
+
+	var buf bytes.Buffer
+	metrics.write(&buf)
+
+	for _, line := range []string{
+		`glacier_http_requests_total{env="prod",service="billing",route="/users/{id}",method="GET",status="200"} 1`,
+		`glacier_http_request_duration_seconds_bucket{env="prod",service="billing",route="/users/{id}",method="GET",status="200",le="0.025"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expect metrics contains %s, got:\n%s", line, buf.String())
+		}
+	}
+}
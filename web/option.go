@@ -1,6 +1,7 @@
 package web
 
 import (
+	"crypto/tls"
 	"time"
 
 	"github.com/mylxsw/glacier/infra"
@@ -68,6 +69,24 @@ func SetMuxRouteHandlerOption(h MuxRouteHandler) Option {
 	}
 }
 
+// SetHttpHandlerOption 注册自定义的 http.Handler（比如 gin，chi 等第三方框架的路由对象），
+// 所有未匹配到 Glacier 路由（SetRouteHandlerOption，SetMuxRouteHandlerOption 注册的路由以及健康检查接口）的请求都会交给该 handler 处理，
+// handler 中的 panic 会被捕获，记录日志之后返回 500 错误
+func SetHttpHandlerOption(builder HttpHandlerBuilder) Option {
+	return func(cc infra.Resolver, conf *Config) {
+		conf.httpHandlerBuilder = builder
+	}
+}
+
+// SetAccessLogOption 启用访问日志，记录每个请求的请求方法，路径，响应状态码，耗时以及客户端 IP，并捕获请求处理过程中的 panic
+// 调试模式（infra.DEBUG）下会额外记录请求头，Authorization，Proxy-Authorization 和 Cookie 始终不会被记录，omittedHeaders 用于指定其它不需要记录的请求头
+func SetAccessLogOption(omittedHeaders ...string) Option {
+	return func(cc infra.Resolver, conf *Config) {
+		conf.accessLog = true
+		conf.omittedHeaders = append(append([]string{}, defaultOmittedHeaders...), omittedHeaders...)
+	}
+}
+
 // SetHttpWriteTimeoutOption set Http write timeout
 func SetHttpWriteTimeoutOption(t time.Duration) Option {
 	return func(cc infra.Resolver, conf *Config) {
@@ -89,6 +108,43 @@ func SetHttpIdleTimeoutOption(t time.Duration) Option {
 	}
 }
 
+// SetShutdownTimeoutOption 设置服务停止时等待处理中的请求完成的最长时间，默认为 5s，超时后剩余的连接会被直接关闭
+// http 服务会在其它服务（比如定时任务）停止之前停止，因此请求处理过程中依然可以使用这些服务
+func SetShutdownTimeoutOption(t time.Duration) Option {
+	return func(cc infra.Resolver, conf *Config) {
+		conf.ShutdownTimeout = t
+	}
+}
+
+// SetLeaderLockOption 只有获取到分布式锁（leader）的节点才会处理请求，其它节点（follower）对所有请求返回 503 Service Unavailable
+// 每隔 checkInterval 调用一次 TryLock 刷新锁，获取失败则失去 leader 身份，checkInterval 小于等于 0 时默认为 10s
+// 监听端口在服务启动前已经绑定，因此 follower 依然会接受连接，负载均衡器应该通过健康检查将返回 503 的节点摘除，
+// leader 切换期间，在新的 leader 获取到锁之前，所有节点都会返回 503
+func SetLeaderLockOption(lock func(cc infra.Resolver) LeaderLock, checkInterval time.Duration) Option {
+	return func(cc infra.Resolver, conf *Config) {
+		conf.leaderLock = lock(cc)
+		conf.leaderCheckInterval = checkInterval
+	}
+}
+
+// SetTLSOption 启用 HTTPS，使用 certFile 和 keyFile 指定的证书启动服务，HTTP/2 默认启用
+// 如果需要同时提供 HTTP 和 HTTPS 服务，使用 RepeatableProvider 注册多个 web.Provider，分别监听不同的端口
+func SetTLSOption(certFile, keyFile string) Option {
+	return func(cc infra.Resolver, conf *Config) {
+		conf.tlsCertFile = certFile
+		conf.tlsKeyFile = keyFile
+	}
+}
+
+// SetTLSConfigOption 启用 HTTPS，并使用自定义的 tls.Config，比如设置 ClientAuth 和 ClientCAs 实现 mTLS 客户端证书校验
+// 如果 tlsConfig 中已经设置了 Certificates 或者 GetCertificate，可以不使用 SetTLSOption 指定证书文件
+// 如果 tlsConfig.NextProtos 中未包含 h2，HTTP/2 会被自动启用
+func SetTLSConfigOption(tlsConfig *tls.Config) Option {
+	return func(cc infra.Resolver, conf *Config) {
+		conf.tlsConfig = tlsConfig
+	}
+}
+
 // SetOptions 设置 options，设置前可以获取到 infra.Resolver 实例
 func SetOptions(setter func(cc infra.Resolver) []Option) Option {
 	return func(resolver infra.Resolver, conf *Config) {
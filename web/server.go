@@ -5,7 +5,6 @@ import (
 	"github.com/pkg/errors"
 	"net"
 	"net/http"
-	"time"
 
 	"github.com/mylxsw/glacier/log"
 
@@ -66,20 +65,39 @@ func (app *serverImpl) Start(listener net.Listener) error {
 
 	app.status = serverStatusStarted
 	return app.cc.Resolve(func(gf infra.Graceful) error {
+		handler, err := app.router(app.cc)
+		if err != nil {
+			return err
+		}
+
+		leaderCtx, stopLeader := context.WithCancel(context.Background())
+		defer stopLeader()
+
+		if app.conf.leaderLock != nil {
+			gate := newLeaderGate(app.conf.leaderLock, app.conf.leaderCheckInterval)
+			handler = gate.wrap(handler)
+			go gate.run(leaderCtx)
+		}
+
 		srv := &http.Server{
-			Handler:           app.router(app.cc),
+			Handler:           handler,
 			WriteTimeout:      app.conf.HttpWriteTimeout,
 			ReadTimeout:       app.conf.HttpReadTimeout,
 			IdleTimeout:       app.conf.HttpIdleTimeout,
 			ReadHeaderTimeout: app.conf.HttpReadHeaderTimeout,
 		}
 
+		if app.conf.tlsConfig != nil {
+			srv.TLSConfig = app.conf.tlsConfig.Clone()
+		}
+
 		if app.conf.serverConfigHandler != nil {
 			app.conf.serverConfigHandler(srv, listener)
 		}
 
-		gf.AddShutdownHandler(func() {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		// http 服务在 pre shutdown 阶段停止，等待处理中的请求完成之后，才会执行其它服务（比如定时任务）的 shutdown handler
+		gf.AddPreShutdownHandler(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), app.conf.ShutdownTimeout)
 			defer cancel()
 
 			if infra.DEBUG {
@@ -88,6 +106,7 @@ func (app *serverImpl) Start(listener net.Listener) error {
 
 			if err := srv.Shutdown(ctx); err != nil {
 				log.Errorf("[glacier] shutdown http server failed: %s", err)
+				_ = srv.Close()
 			}
 
 			if infra.DEBUG {
@@ -99,7 +118,7 @@ func (app *serverImpl) Start(listener net.Listener) error {
 			log.Debugf("[glacier] http server started, listening on %s", listener.Addr())
 		}
 
-		if err := srv.Serve(listener); err != nil {
+		if err := app.serve(srv, listener); err != nil {
 			if infra.DEBUG {
 				log.Debugf("[glacier] http server stopped: %s", err)
 			}
@@ -113,7 +132,16 @@ func (app *serverImpl) Start(listener net.Listener) error {
 	})
 }
 
-func (app *serverImpl) router(cc infra.Container) http.Handler {
+// serve 启动 http 服务，启用 TLS 时，HTTP/2 由 net/http 自动配置
+func (app *serverImpl) serve(srv *http.Server, listener net.Listener) error {
+	if app.conf.tlsEnabled() {
+		return srv.ServeTLS(listener, app.conf.tlsCertFile, app.conf.tlsKeyFile)
+	}
+
+	return srv.Serve(listener)
+}
+
+func (app *serverImpl) router(cc infra.Container) (http.Handler, error) {
 	router := NewRouterWithContainer(cc, app.conf)
 	mw := NewRequestMiddleware()
 
@@ -121,11 +149,46 @@ func (app *serverImpl) router(cc infra.Container) http.Handler {
 		app.conf.routeHandler(cc, router, mw)
 	}
 
-	return router.Perform(app.conf.exceptionHandler, func(muxRouter *mux.Router) {
-		if app.conf.muxRouteHandler == nil {
-			return
+	var customHandler http.Handler
+	if app.conf.httpHandlerBuilder != nil {
+		h, err := app.conf.httpHandlerBuilder(cc)
+		if err != nil {
+			return nil, errors.Wrap(err, "[glacier] create http handler failed")
+		}
+
+		customHandler = recoverHandler(h)
+	}
+
+	var metrics *requestMetrics
+	handler := router.Perform(app.conf.exceptionHandler, func(muxRouter *mux.Router) {
+		// the health check endpoint is enabled by app.WithHealthCheck
+		_ = cc.Resolve(func(hc *infra.HealthCheck) {
+			muxRouter.Handle(hc.Path, newHealthHandler(cc, hc)).Methods(http.MethodGet)
+		})
+
+		// the metrics endpoint is enabled by app.WithPrometheus
+		_ = cc.Resolve(func(conf *infra.Metrics) {
+			metrics = newRequestMetrics(muxRouter, infra.DefaultLabels(cc))
+			muxRouter.Handle(conf.Path, newMetricsHandler(cc, conf, metrics)).Methods(http.MethodGet)
+		})
+
+		if app.conf.muxRouteHandler != nil {
+			app.conf.muxRouteHandler(cc, muxRouter)
 		}
 
-		app.conf.muxRouteHandler(cc, muxRouter)
+		// the custom handler is registered at last, so that all glacier routes take precedence over it
+		if customHandler != nil {
+			muxRouter.PathPrefix("/").Handler(customHandler)
+		}
 	})
+
+	if app.conf.accessLog {
+		handler = accessLogHandler(handler, app.conf.omittedHeaders)
+	}
+
+	if metrics != nil {
+		handler = metrics.wrap(handler)
+	}
+
+	return handler, nil
 }